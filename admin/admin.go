@@ -0,0 +1,266 @@
+// Package admin 为 crab 应用提供管理端 HTTP 服务：K8S 探针、健康检查详情、组件生命周期、
+// pprof 与 expvar，以及可选的 POST /shutdown。
+//
+// net/http/pprof 与 expvar 在 import 时会向 http.DefaultServeMux 注册 /debug/pprof/* 与 /debug/vars，
+// 因此管理端单独成包：只有 import 了本包的程序才会带上这些路由。
+//
+//	app := crab.New()
+//	if _, err := admin.Listen(app, ":9090", admin.WithShutdownToken(os.Getenv("ADMIN_TOKEN"))); err != nil {
+//		log.Fatal(err)
+//	}
+//	app.Run()
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bang-go/crab"
+)
+
+// shutdownGrace 应用关闭后等待管理端请求结束的时间，之后强制关闭剩余连接
+const shutdownGrace = time.Second
+
+// Option 定义管理端的配置选项
+type Option func(*Server)
+
+// WithShutdownToken 开启 POST /shutdown 接口，请求需携带 "Authorization: Bearer <token>" 头
+func WithShutdownToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// Server 是应用的管理端，提供以下接口：
+//
+//	/livez           存活探针，关键健康检查失败时返回 503
+//	/readyz          就绪探针，应用未运行（包括排空流量期间及 Stop 开始后）或有检查失败时返回 503
+//	/healthz         JSON 格式的健康检查详情
+//	/lifecycle       JSON 格式的钩子状态及启动/关闭耗时
+//	/debug/pprof/*   net/http/pprof
+//	/debug/vars      expvar
+//	POST /shutdown   触发 App.Stop，需配合 WithShutdownToken 开启
+type Server struct {
+	app   *crab.App
+	token string
+	mux   *http.ServeMux
+
+	mu    sync.Mutex
+	state string // 由生命周期事件推导的应用状态
+	srv   *http.Server
+	addr  net.Addr
+	done  chan struct{}
+	err   error
+}
+
+// New 创建 app 的管理端 http.Handler，可挂载到业务自己的 ServeMux 上。
+// 应在 Run 之前调用，以便 /lifecycle 跟踪应用状态
+func New(app *crab.App, opts ...Option) *Server {
+	s := &Server{app: app, state: "new"}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/livez", s.handleLivez)
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/lifecycle", s.handleLifecycle)
+	s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.mux.Handle("/debug/vars", expvar.Handler())
+	if s.token != "" {
+		s.mux.HandleFunc("POST /shutdown", s.handleShutdown)
+	}
+	app.Subscribe(s.observe)
+	return s
+}
+
+// Listen 监听 addr 并在后台提供管理端服务，先于所有钩子启动，在应用关闭流程结束（所有钩子关闭）后关闭。
+// 监听失败时返回错误；服务异常退出时停止应用，错误可以通过 Err 获取
+func Listen(app *crab.App, addr string, opts ...Option) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := New(app, opts...)
+	s.mu.Lock()
+	s.srv = &http.Server{Handler: s, ReadHeaderTimeout: 5 * time.Second}
+	s.addr = ln.Addr()
+	s.done = make(chan struct{})
+	srv, done := s.srv, s.done
+	s.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			_ = app.Stop(context.Background())
+		}
+	}()
+	return s, nil
+}
+
+// Addr 返回管理端实际监听的地址，未通过 Listen 创建时返回空字符串
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.addr == nil {
+		return ""
+	}
+	return s.addr.String()
+}
+
+// Err 返回管理端服务异常退出的错误
+func (s *Server) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// observe 跟踪应用状态，关闭流程结束后关闭管理端
+func (s *Server) observe(e crab.Event) {
+	var state string
+	switch e.Type {
+	case crab.EventAppStarting:
+		state = "starting"
+	case crab.EventAppStarted:
+		state = "running"
+	case crab.EventAppStopping:
+		state = "stopping"
+	case crab.EventAppStopped:
+		state = "stopped"
+	default:
+		return
+	}
+
+	s.mu.Lock()
+	s.state = state
+	srv, done := s.srv, s.done
+	s.mu.Unlock()
+	if state != "stopped" || srv == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		_ = srv.Close()
+	}
+	<-done
+}
+
+func (s *Server) handleLivez(w http.ResponseWriter, r *http.Request) {
+	if !s.app.Health(r.Context()).Live {
+		http.Error(w, "not live", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.app.Health(r.Context()).Ready {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	report := s.app.Health(r.Context())
+	code := http.StatusOK
+	if report.Status == crab.HealthDown {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
+
+func (s *Server) handleLifecycle(w http.ResponseWriter, r *http.Request) {
+	type hookView struct {
+		crab.HookInfo
+		StartCostText string `json:"start_cost,omitempty"`
+		StopCostText  string `json:"stop_cost,omitempty"`
+	}
+
+	hooks := s.app.Inspect()
+	views := make([]hookView, len(hooks))
+	for k, h := range hooks {
+		views[k].HookInfo = h
+		if h.StartCost > 0 {
+			views[k].StartCostText = formatCost(h.StartCost)
+		}
+		if h.StopCost > 0 {
+			views[k].StopCostText = formatCost(h.StopCost)
+		}
+	}
+
+	s.mu.Lock()
+	state := s.state
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"app_id": s.app.GetID(),
+		"state":  state,
+		"hooks":  views,
+	})
+}
+
+func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte("shutting down"))
+	// 在独立 goroutine 中停止，避免管理端等待自身请求结束
+	go func() { _ = s.app.Stop(context.Background()) }()
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// formatCost 与 crab 日志中的耗时格式一致，如 "12ms"、"1.204s"
+func formatCost(d time.Duration) string {
+	if d >= time.Second {
+		secs := d.Seconds()
+		if secs < 10 {
+			return fmt.Sprintf("%.3fs", secs)
+		}
+		if secs < 100 {
+			return fmt.Sprintf("%.2fs", secs)
+		}
+		return fmt.Sprintf("%.0fs", secs)
+	}
+	if ms := d.Milliseconds(); ms > 0 {
+		return fmt.Sprintf("%dms", ms)
+	}
+	if us := d.Microseconds(); us > 0 {
+		return fmt.Sprintf("%dµs", us)
+	}
+	return fmt.Sprintf("%dns", d.Nanoseconds())
+}
@@ -0,0 +1,37 @@
+package crab
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// backoffDelay 计算第 attempt 次（从 1 开始）重试前的指数退避时间，不超过 maxDelay。
+// jitter 在 (0, 1] 之间时，结果在 ±jitter 比例内随机浮动
+func backoffDelay(attempt int, initial, maxDelay time.Duration, jitter float64) time.Duration {
+	d := initial
+	for n := 1; n < attempt && d < maxDelay; n++ {
+		d *= 2
+	}
+	d = min(d, maxDelay)
+	if jitter > 0 {
+		jitter = min(jitter, 1)
+		d = time.Duration(float64(d) * (1 + jitter*(2*rand.Float64()-1)))
+	}
+	return d
+}
+
+// sleepContext 等待 d 或 ctx 结束，ctx 先结束时返回其错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,65 @@
+package crab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/pprof"
+	"time"
+
+	"github.com/bang-go/crab/pkg/types"
+)
+
+// stuckGrace 是 Context 结束后等待钩子自行返回的时间，超过后视为卡死并放弃
+const stuckGrace = 50 * time.Millisecond
+
+// callHook 在带有 pprof 标签的 goroutine 中执行钩子的某个阶段。
+// timeout > 0 时为该阶段单独设置超时；ctx 结束而钩子仍未返回时放弃等待，
+// 记录卡死日志并输出该钩子所有 goroutine 的调用栈，使调用方可以继续后续流程
+func (a *App) callHook(ctx context.Context, i int, phase Phase, timeout time.Duration, fn types.Runner) error {
+	parent := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	name := hookName(a.hooks[i], i)
+	done := make(chan error, 1)
+	labels := pprof.Labels(labelApp, a.id, labelHook, name, labelPhase, string(phase))
+	go pprof.Do(ctx, labels, func(ctx context.Context) {
+		done <- safeCall(ctx, fn)
+	})
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// 响应 ctx 的钩子通常会立即返回，给它一个短暂的机会
+		select {
+		case err = <-done:
+		case <-time.After(stuckGrace):
+			a.reportStuck(name, phase, ctx.Err())
+			err = fmt.Errorf("abandoned: %w", ctx.Err())
+		}
+	}
+
+	return timeoutError(parent, ctx, phase, timeout, err)
+}
+
+// timeoutError 在钩子自身的超时（而非上层 Context）导致失败时，将 err 包装为 ErrHookTimeout
+func timeoutError(parent, ctx context.Context, phase Phase, timeout time.Duration, err error) error {
+	if err != nil && timeout > 0 && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s exceeded %v: %w", ErrHookTimeout, phase, timeout, err)
+	}
+	return err
+}
+
+// reportStuck 记录卡死的钩子及其 goroutine 调用栈
+func (a *App) reportStuck(name string, phase Phase, cause error) {
+	stacks := hookStacks(a.id, name)
+	if stacks == "" {
+		stacks = "(no goroutines found)"
+	}
+	a.err("Component stuck, abandoning", "hook", name, "phase", phase, "error", cause, "goroutines", stacks)
+}
@@ -0,0 +1,193 @@
+package crab
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Component 是一个类型安全的组件句柄：值由构造函数在组件的 OnStart 中创建，
+// 只有启动完成之后、关闭之前才能通过 Get 取得，避免在配置加载前读取配置等顺序错误
+// 以 nil 指针的形式出现在运行期间。
+//
+//	db := crab.NewComponent(lc, "db", func(ctx context.Context) (*sql.DB, error) {
+//		return sql.Open("mysql", conf.MustGetContext(ctx).DSN) // 以 OnStart 的 ctx 读取，crab 会记录 db 使用了 config
+//	}, func(ctx context.Context, db *sql.DB) error {
+//		return db.Close()
+//	}, "config")
+type Component[T any] struct {
+	name string
+
+	mu    sync.RWMutex
+	state componentState
+	value T
+	app   *App // 启动后记录所在的应用与钩子下标，用于记录组件之间的使用关系
+	index int
+}
+
+type componentState int
+
+const (
+	componentPending componentState = iota
+	componentReady
+	componentStopped
+)
+
+// NewComponent 创建组件并通过 lc 注册名为 name 的钩子：OnStart 调用 ctor 创建值，
+// OnStop 调用 closer 释放值（closer 可以为 nil）。dependsOn 为组件依赖的其他钩子或组件名称
+func NewComponent[T any](lc Lifecycle, name string, ctor func(context.Context) (T, error), closer func(context.Context, T) error, dependsOn ...string) *Component[T] {
+	c := &Component[T]{name: name}
+	lc.Append(Hook{
+		Name:      name,
+		DependsOn: dependsOn,
+		OnStart:   c.start(ctor),
+		OnStop:    c.stop(closer),
+		Source:    callerSource(1),
+	})
+	return c
+}
+
+// Name 返回组件名称
+func (c *Component[T]) Name() string {
+	return c.name
+}
+
+// Get 返回组件的值；组件尚未启动完成或已经关闭时返回包装了 ErrComponentNotReady 的错误。
+// Get 不记录调用方，在其他钩子的 OnStart 中应使用 GetContext，以便 crab 检查依赖声明
+func (c *Component[T]) Get() (T, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch c.state {
+	case componentPending:
+		var zero T
+		return zero, fmt.Errorf("%w: component [%s] has not started", ErrComponentNotReady, c.name)
+	case componentStopped:
+		var zero T
+		return zero, fmt.Errorf("%w: component [%s] has been stopped", ErrComponentNotReady, c.name)
+	}
+	return c.value, nil
+}
+
+// MustGet 与 Get 相同，组件不可用时 panic；同样不记录调用方，在其他钩子的 OnStart 中应使用 MustGetContext
+func (c *Component[T]) MustGet() T {
+	v, err := c.Get()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// GetContext 与 Get 相同；在其他钩子的 OnStart 中以其 ctx 调用时，crab 会记录两者的使用关系：
+// 组件尚未启动时错误中包含调用方的名称，使用方没有（直接或间接）依赖该组件时记录警告，
+// 让顺序错误在启动阶段暴露。使用关系可以通过 App.Inspect 查看
+func (c *Component[T]) GetContext(ctx context.Context) (T, error) {
+	v, err := c.Get()
+	user, ok := ctx.Value(hookKey{}).(hookRef)
+	if !ok {
+		return v, err
+	}
+	if err != nil {
+		return v, fmt.Errorf("%w (used by [%s], declare it in DependsOn)", err, hookName(user.app.hooks[user.index], user.index))
+	}
+
+	c.mu.RLock()
+	app, index := c.app, c.index
+	c.mu.RUnlock()
+	if app == user.app {
+		app.recordUse(user.index, index)
+	}
+	return v, nil
+}
+
+// MustGetContext 与 GetContext 相同，组件不可用时 panic（panic 的值为 GetContext 返回的错误）
+func (c *Component[T]) MustGetContext(ctx context.Context) T {
+	v, err := c.GetContext(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (c *Component[T]) start(ctor func(context.Context) (T, error)) func(context.Context) error {
+	return func(ctx context.Context) error {
+		v, err := ctor(ctx)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.value, c.state = v, componentReady
+		if ref, ok := ctx.Value(hookKey{}).(hookRef); ok {
+			c.app, c.index = ref.app, ref.index
+		}
+		return nil
+	}
+}
+
+func (c *Component[T]) stop(closer func(context.Context, T) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		c.mu.Lock()
+		v := c.value
+		var zero T
+		c.value, c.state = zero, componentStopped
+		c.mu.Unlock()
+		if closer == nil {
+			return nil
+		}
+		return closer(ctx, v)
+	}
+}
+
+// hookKey 是钩子 OnStart 的 Context 中记录当前钩子的键
+type hookKey struct{}
+
+type hookRef struct {
+	app   *App
+	index int
+}
+
+// recordUse 记录钩子 i 在启动时使用了钩子 j 注册的组件
+func (a *App) recordUse(i, j int) {
+	a.markHook(i, func(s *hookStatus) {
+		for _, u := range s.uses {
+			if u == j {
+				return
+			}
+		}
+		s.uses = append(s.uses, j)
+	})
+}
+
+// checkUses 在钩子 i 启动完成后检查其使用的组件，没有声明依赖时记录警告：
+// 串行启动时这类顺序可能碰巧正确，但调整注册顺序或开启并发启动后就会出错
+func (a *App) checkUses(i int) {
+	a.mu.Lock()
+	uses := append([]int(nil), a.status[i].uses...)
+	a.mu.Unlock()
+	for _, j := range uses {
+		if !a.reaches(i, j) {
+			a.warn("Component uses another component without depending on it",
+				"hook", hookName(a.hooks[i], i), "phase", PhaseStart, "uses", hookName(a.hooks[j], j))
+		}
+	}
+}
+
+// reaches 判断钩子 i 是否（直接或间接）依赖钩子 j
+func (a *App) reaches(i, j int) bool {
+	seen := make(map[int]bool)
+	stack := []int{i}
+	for len(stack) > 0 {
+		k := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, d := range a.deps[k] {
+			if d == j {
+				return true
+			}
+			if !seen[d] {
+				seen[d] = true
+				stack = append(stack, d)
+			}
+		}
+	}
+	return false
+}
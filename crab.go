@@ -12,9 +12,10 @@ import (
 	"time"
 
 	"github.com/bang-go/crab/pkg/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Logger 定义日志接口 (兼容 bang-go/micro/logger)
+// Logger 定义日志接口 (兼容 bang-go/micro/logger)，同时实现 Debug / Warn 时见 LeveledLogger
 type Logger interface {
 	Info(ctx context.Context, msg string, args ...interface{})
 	Error(ctx context.Context, msg string, args ...interface{})
@@ -22,9 +23,36 @@ type Logger interface {
 
 // Hook 定义应用生命周期中的一个钩子
 type Hook struct {
-	Name    string // 组件名称，用于日志标识
+	Name      string   // 组件名称，用于日志标识及 DependsOn 引用
+	DependsOn []string // 依赖的钩子名称：启动时排在依赖之后，关闭时排在依赖之前
+	// Source 注册钩子的位置，如 "redis.NewClient (redis/client.go:42)"，由 App.Add 与 Registry.Append 自动记录；
+	// 未命名的钩子在日志和错误中使用它作为名称
+	Source  string
 	OnStart types.Runner
 	OnStop  types.Stopper
+	// StartTimeout OnStart 的超时时间，超时后放弃该钩子并输出其 goroutine 调用栈；0 表示只受全局启动超时限制
+	StartTimeout time.Duration
+	// StopTimeout 关闭该钩子的超时时间，超时后放弃该钩子并继续关闭其余钩子；0 表示只受全局关闭超时限制
+	StopTimeout time.Duration
+	// Retry OnStart 失败后的重试策略，nil 表示不重试
+	Retry *RetryPolicy
+	// Serve 长期运行的服务函数（如 ListenAndServe），在 OnStart 成功后由 crab 在独立 goroutine 中运行。
+	// 关闭前返回非 nil 错误会触发应用优雅关闭，并作为 Run 的返回值；
+	// 关闭该组件时先取消其 Context，再调用 OnStop，最后等待 Serve 返回
+	Serve types.Runner
+	// Restart Serve 退出后的重启策略，nil 表示不重启
+	Restart *RestartPolicy
+	// Health 可选的健康检查，由 App.Health 聚合
+	Health *HealthCheck
+	// OnReload 收到重载信号或调用 App.Reload 时按依赖顺序调用，用于重新加载配置
+	OnReload types.Runner
+	// OnReloadRollback 后续钩子重载失败时，用于撤销本钩子已生效的重载
+	OnReloadRollback types.Runner
+	// Critical 标记强制关闭（关闭期间再次收到信号）时仍需执行的钩子，如提交消费位点、刷写事务日志
+	Critical bool
+
+	module   []Hook    // 由 Module 创建的模块包含的钩子，Run 时展开
+	provider *provider // 通过注入的 Lifecycle 注册该钩子的构造函数
 }
 
 // Option 定义配置选项
@@ -36,13 +64,51 @@ type App struct {
 	ctx               context.Context
 	cancel            context.CancelFunc
 	hooks             []Hook
+	modules           [][]*moduleRun       // 每个钩子所在的模块链，由 Run 展开模块时生成
+	container         *container           // Provide / Invoke 注册的依赖注入容器
+	order             []int                // 按依赖拓扑排序后的 hooks 下标
+	deps              [][]int              // 每个钩子直接依赖的 hooks 下标
+	started           []int                // 已成功启动的 hooks 下标，按启动完成顺序
+	status            []hookStatus         // 每个钩子的运行时状态，用于 Inspect
+	serving           map[int]*serveHandle // 运行中的 Serve，key 为 hooks 下标，由 serveMu 保护
+	serveClosed       bool                 // 关闭流程已开始，不再接受新的 Serve
+	serveMu           sync.Mutex
+	restartMu         sync.Mutex           // 串行化监督者触发的组件重启，关闭流程开始时等待进行中的重启结束
+	restartCtx        context.Context      // 关闭流程开始后取消，中止监督者的退避等待与进行中的重启
+	restartCancel     context.CancelFunc   //
+	down              map[int]bool         // 被监督者停止、尚未重新启动的钩子，关闭时跳过；由 restartMu 保护，写入时同时持有 mu，只读时持有其一即可
+	serveErr          chan error           // 第一个导致应用关闭的组件错误
+	stopDone          chan struct{}        // Stop 完成后关闭
+	stopErr           error                // Stop 的结果
+	health            map[int]*healthEntry // 健康检查结果缓存，由 healthMu 保护
+	healthCacheTTL    time.Duration
+	healthMu          sync.Mutex
 	shutdownTimeout   time.Duration
-	startupTimeout    time.Duration // 启动超时
+	shutdownPolicy    ShutdownPolicy
+	drainDelay        time.Duration // 收到关闭信号后、开始关闭前等待流量排空的时间
+	draining          bool
+	signalPolicy      SignalPolicy
+	notifier          SignalNotifier
+	leakCheck         bool
+	forced            chan struct{}           // 进入强制关闭后关闭
+	forceCtx          context.Context         // 强制关闭时关键钩子共用的 Context
+	forceCancel       context.CancelFunc      //
+	stopCancel        context.CancelCauseFunc // 中止正在进行的关闭流程
+	startupTimeout    time.Duration           // 启动超时
+	parallel          bool                    // 是否并行启动/关闭无依赖约束的钩子
+	maxConcurrency    int                     // 并行模式下的最大并发数，<= 0 表示不限制
 	signals           []os.Signal
-	logger            Logger // 日志接口
+	reloadSignals     []os.Signal
+	reloadTimeout     time.Duration // 单次重载的超时时间
+	reloadSem         chan struct{} // 容量为 1，串行化 Reload；用 channel 而非 Mutex，使等待在 testing/synctest 中视为阻塞
+	logger            Logger        // 日志接口
+	tracer            trace.Tracer
 	mu                sync.Mutex
 	state             state
-	shutdownCallbacks []func() // shutdown回调函数
+	shutdownCallbacks []func()   // shutdown回调函数
+	observers         []observer // 生命周期事件观察者，由 obsMu 保护
+	obsSeq            int
+	obsMu             sync.RWMutex
 }
 
 type state int
@@ -55,17 +121,51 @@ const (
 	stateStopped
 )
 
+func (s state) String() string {
+	switch s {
+	case stateNew:
+		return "new"
+	case stateStarting:
+		return "starting"
+	case stateRunning:
+		return "running"
+	case stateStopping:
+		return "stopping"
+	case stateStopped:
+		return "stopped"
+	}
+	return fmt.Sprintf("state(%d)", int(s))
+}
+
 // New 创建一个新的应用实例
 func New(opts ...Option) *App {
 	ctx, cancel := context.WithCancel(context.Background())
+	restartCtx, restartCancel := context.WithCancel(context.Background())
 	app := &App{
 		id:                generateAppID(),
 		ctx:               ctx,
 		cancel:            cancel,
 		shutdownTimeout:   10 * time.Second,
+		shutdownPolicy:    ShutdownPolicy{Mode: ShutdownAbort, Grace: defaultShutdownGrace},
 		startupTimeout:    0, // 默认无超时
 		signals:           []os.Signal{syscall.SIGTERM, syscall.SIGINT},
+		reloadSignals:     []os.Signal{syscall.SIGHUP},
+		reloadTimeout:     defaultReloadTimeout,
+		reloadSem:         make(chan struct{}, 1),
+		signalPolicy:      SignalPolicy{}.withDefaults(),
+		notifier:          osSignals{},
+		forced:            make(chan struct{}),
 		state:             stateNew,
+		serving:           make(map[int]*serveHandle),
+		restartCtx:        restartCtx,
+		restartCancel:     restartCancel,
+		down:              make(map[int]bool),
+		serveErr:          make(chan error, 1),
+		stopDone:          make(chan struct{}),
+		health:            make(map[int]*healthEntry),
+		healthCacheTTL:    defaultHealthCacheTTL,
+		tracer:            defaultTracer(),
+		logger:            defaultLogger(),
 		shutdownCallbacks: make([]func(), 0),
 	}
 
@@ -94,6 +194,15 @@ func WithShutdownTimeout(d time.Duration) Option {
 	}
 }
 
+// WithDrainDelay 设置收到关闭信号后的排空等待时间：应用先标记为未就绪（IsReady 返回 false），
+// 等待 d 让负载均衡摘除流量，再开始关闭组件。等待期间再次收到信号会立即进入强制关闭（见 WithSignalPolicy）。
+// 排空时间不计入 WithShutdownTimeout
+func WithDrainDelay(d time.Duration) Option {
+	return func(a *App) {
+		a.drainDelay = d
+	}
+}
+
 // WithStartupTimeout 设置启动超时时间
 func WithStartupTimeout(d time.Duration) Option {
 	return func(a *App) {
@@ -101,6 +210,15 @@ func WithStartupTimeout(d time.Duration) Option {
 	}
 }
 
+// WithParallelLifecycle 开启并行生命周期：没有依赖约束的钩子按层级并发启动，
+// 关闭时按相反层级并发停止。maxConcurrency 限制同时执行的钩子数量，<= 0 表示不限制
+func WithParallelLifecycle(maxConcurrency int) Option {
+	return func(a *App) {
+		a.parallel = true
+		a.maxConcurrency = maxConcurrency
+	}
+}
+
 // WithSignals 设置监听的系统信号
 func WithSignals(signals ...os.Signal) Option {
 	return func(a *App) {
@@ -108,7 +226,30 @@ func WithSignals(signals ...os.Signal) Option {
 	}
 }
 
-// WithLogger 设置日志接口
+// SignalNotifier 负责将系统信号转发到 channel，默认实现为 os/signal
+type SignalNotifier interface {
+	Notify(c chan<- os.Signal, sig ...os.Signal)
+	Stop(c chan<- os.Signal)
+}
+
+// osSignals 使用 os/signal 转发真实的系统信号
+type osSignals struct{}
+
+func (osSignals) Notify(c chan<- os.Signal, sig ...os.Signal) { signal.Notify(c, sig...) }
+func (osSignals) Stop(c chan<- os.Signal)                     { signal.Stop(c) }
+
+// WithSignalNotifier 替换信号来源，测试中可以注入假的实现（见 crabtest.Signals）按需投递信号
+func WithSignalNotifier(n SignalNotifier) Option {
+	return func(a *App) {
+		if n == nil {
+			n = osSignals{}
+		}
+		a.notifier = n
+	}
+}
+
+// WithLogger 设置日志接口，传入 nil 关闭 crab 的内部日志。
+// 未设置时输出到 stderr，级别由环境变量 CRAB_LOG_LEVEL 控制
 func WithLogger(l Logger) Option {
 	return func(a *App) {
 		a.logger = l
@@ -123,50 +264,138 @@ func (a *App) Add(hooks ...Hook) {
 	if a.state > stateNew {
 		panic("crab: cannot add hook after app has started")
 	}
-	a.hooks = append(a.hooks, hooks...)
+	for _, h := range hooks {
+		a.hooks = append(a.hooks, withSource(h, 1))
+	}
 }
 
-// IsRunning 返回应用是否处于运行状态（Ready）
+// IsRunning 返回应用是否处于运行状态，排空流量期间仍为 true
 func (a *App) IsRunning() bool {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	return a.state == stateRunning
 }
 
+// IsReady 返回应用是否可以接收流量：处于运行状态且未在排空流量
+func (a *App) IsReady() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.state == stateRunning && !a.draining
+}
+
 // Run 启动应用并阻塞，直到收到信号或发生错误
 func (a *App) Run() error {
 	if !a.changeState(stateNew, stateStarting) {
-		return errors.New("app already started")
+		return ErrAlreadyStarted
+	}
+	if !a.leakCheck {
+		return a.run()
 	}
+	baseline := goroutineIDs()
+	err := a.run()
+	return errors.Join(err, a.checkLeaks(baseline))
+}
 
+func (a *App) run() error {
 	a.log("App starting...")
+	a.emit(Event{Type: EventAppStarting})
 	startBegin := time.Now()
 
-	// 1. 启动流程 (带超时控制)
-	if err := a.runStartWithTimeout(); err != nil {
-		// 启动失败，执行回滚（停止已启动的组件）
-		a.log("App start failed. Rolling back...", "error", err)
-		_ = a.stop(context.Background())
+	// 0. 调用 Invoke 构造组件，解析依赖，计算启动顺序
+	if err := a.plan(); err != nil {
+		a.changeState(stateStarting, stateStopped)
+		_ = globalShutdown.Unregister(a.id)
+		a.emit(Event{Type: EventAppStopped, Err: err})
 		return err
 	}
 
-	a.log("App started successfully", "cost", formatCost(time.Since(startBegin)))
-	a.changeState(stateStarting, stateRunning)
+	// 1. 启动流程 (带超时控制)
+	startCtx, span := a.startSpan(a.ctx, "crab.startup")
+	if se := a.runStartWithTimeout(startCtx); se != nil {
+		// 启动失败，执行回滚（停止已启动的组件）
+		a.err("App start failed. Rolling back...", "hook", se.Hook, "phase", se.Phase, "error", se)
+		a.emit(Event{Type: EventRollback, Hook: se.Hook, Phase: se.Phase, Err: se, Panic: se.Panic})
+		span.AddEvent("rollback", trace.WithAttributes(attrHook.String(se.Hook)))
+		if a.changeState(stateStarting, stateStopping) {
+			se.Rollback = a.stop(trace.ContextWithSpan(context.Background(), span)) // 回滚的 crab.shutdown 嵌套在 crab.startup 下
+		} else {
+			// 启动期间调用了 Stop（启动因此被取消），已启动的组件由 Stop 关闭，不再重复关闭
+			<-a.stopDone
+			a.mu.Lock()
+			se.Rollback = a.stopErr
+			a.mu.Unlock()
+		}
+		endSpan(span, se)
+		return se
+	}
+	endSpan(span, nil)
 
-	// 2. 等待信号
+	// 2. 等待信号：在宣告启动完成之前开始监听，EventAppStarted 之后投递的信号不会丢失
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, a.signals...)
-
-	select {
-	case sig := <-c:
-		a.log("Received signal", "signal", sig)
-		signal.Stop(c)
-	case <-a.ctx.Done():
-		a.log("Context canceled")
+	a.notifier.Notify(c, a.signals...)
+	reload := make(chan os.Signal, 1)
+	if len(a.reloadSignals) > 0 {
+		a.notifier.Notify(reload, a.reloadSignals...)
 	}
 
-	// 3. 关闭流程
-	return a.Stop(context.Background())
+	startCost := time.Since(startBegin)
+	a.log("App started successfully", "cost", formatCost(startCost))
+	a.changeState(stateStarting, stateRunning)
+	a.emit(Event{Type: EventAppStarted, Duration: startCost})
+
+	// 重载在独立的 goroutine 中执行（由 reloadSem 串行化），卡住的 OnReload 不会阻塞信号与组件错误的处理；
+	// 离开等待循环时取消进行中的重载
+	reloadCtx, cancelReload := context.WithCancel(a.ctx)
+	var reloads sync.WaitGroup
+	defer reloads.Wait()
+
+	var runErr error
+	signaled := false
+wait:
+	for {
+		select {
+		case sig := <-c:
+			a.log("Received signal", "signal", sig)
+			a.emit(Event{Type: EventSignal, Signal: sig, Attempt: 1})
+			signaled = true
+			break wait
+		case sig := <-reload:
+			a.log("Received reload signal", "signal", sig)
+			a.emit(Event{Type: EventSignal, Signal: sig})
+			reloads.Go(func() {
+				_ = a.Reload(reloadCtx) // 结果已通过 Logger 与事件上报
+			})
+		case <-a.ctx.Done():
+			a.log("Context canceled")
+			break wait
+		case runErr = <-a.serveErr:
+			a.err("Component exited unexpectedly, shutting down...", "error", runErr)
+			break wait
+		}
+	}
+	a.notifier.Stop(reload)
+	cancelReload()
+
+	// 3. 关闭流程（Stop 可能已由其他 goroutine 发起，等待其完成）。
+	// 关闭期间继续监听信号：再次收到信号时升级为强制关闭，多次收到时立即退出
+	stopWatch := a.watchSignals(c)
+	if signaled {
+		runErr = a.drain()
+	}
+	_ = a.Stop(context.Background())
+	<-a.stopDone
+	stopWatch()
+	a.notifier.Stop(c)
+	a.mu.Lock()
+	if a.forceCancel != nil {
+		a.forceCancel()
+	}
+	err := a.stopErr
+	a.mu.Unlock()
+	if err != nil {
+		return errors.Join(runErr, err)
+	}
+	return runErr
 }
 
 // Stop 手动停止应用
@@ -180,8 +409,12 @@ func (a *App) Stop(ctx context.Context) error {
 	a.mu.Unlock()
 
 	a.log("App stopping...")
-	a.cancel() // 取消主 Context
+	a.emit(Event{Type: EventAppStopping})
+	a.cancel() // 取消主 Context，同时中止进行中的重载
 
+	// 等待被中止的重载结束，避免 OnReload 与 OnStop 并发执行
+	a.reloadSem <- struct{}{}
+	<-a.reloadSem
 	a.mu.Lock()
 	callbacks := append([]func(){}, a.shutdownCallbacks...)
 	a.mu.Unlock()
@@ -191,21 +424,62 @@ func (a *App) Stop(ctx context.Context) error {
 			defer func() {
 				if r := recover(); r != nil {
 					a.err("Shutdown callback panicked", "panic", r)
+					a.emit(Event{Type: EventShutdownCallbackPanic, Panic: r})
 				}
 			}()
 			callback()
 		}()
 	}
 
-	// 创建带超时的 context 用于停止流程
+	// 创建带超时的 context 用于停止流程，进入强制关闭时立即取消
+	ctx, cancelStop := context.WithCancelCause(ctx)
+	defer cancelStop(nil)
+	a.mu.Lock()
+	a.stopCancel = cancelStop
+	forced := a.forceCtx != nil
+	a.mu.Unlock()
+	if forced {
+		cancelStop(ErrForcedShutdown)
+	}
 	shutdownCtx, cancel := context.WithTimeout(ctx, a.shutdownTimeout)
 	defer cancel()
 
 	err := a.stop(shutdownCtx)
 	_ = globalShutdown.Unregister(a.id)
+
+	a.mu.Lock()
+	a.stopErr = err
+	a.mu.Unlock()
+	close(a.stopDone)
 	return err
 }
 
+// drain 在关闭前将应用标记为未就绪并等待 drainDelay；
+// 再次收到信号（进入强制关闭）、应用被其他调用方停止或组件异常退出时提前结束，返回组件的错误
+func (a *App) drain() error {
+	if a.drainDelay <= 0 {
+		return nil
+	}
+	a.mu.Lock()
+	a.draining = true
+	a.mu.Unlock()
+	a.log("Draining before shutdown...", "delay", formatCost(a.drainDelay))
+	a.emit(Event{Type: EventAppDraining, Duration: a.drainDelay})
+
+	t := time.NewTimer(a.drainDelay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-a.forced:
+		a.log("Skipping drain")
+	case <-a.ctx.Done():
+	case err := <-a.serveErr:
+		a.err("Component exited unexpectedly, shutting down...", "error", err)
+		return err
+	}
+	return nil
+}
+
 func (a *App) changeState(from, to state) bool {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -216,110 +490,234 @@ func (a *App) changeState(from, to state) bool {
 	return true
 }
 
-// runStartWithTimeout 包装启动流程，支持超时
-func (a *App) runStartWithTimeout() error {
+// plan 调用 Invoke 构造组件、展开模块并计算钩子的启动顺序
+func (a *App) plan() error {
+	if a.container != nil {
+		if err := a.container.run(a); err != nil {
+			a.err("Failed to construct components", "error", err)
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	hooks, modules, err := flattenModules(a.hooks)
+	if err == nil {
+		a.hooks, a.modules = hooks, modules
+		a.order, a.deps, err = resolveOrder(a.hooks)
+	}
+	a.status = make([]hookStatus, len(a.hooks))
+	for i := range a.status {
+		a.status[i].state = HookPending
+	}
+	if err != nil {
+		a.err("Invalid hook dependencies", "error", err)
+	}
+	return err
+}
+
+// runStartWithTimeout 包装启动流程，支持超时，失败时返回 *StartError。
+// 卡住的钩子会在 Context 结束后被放弃（见 callHook），因此启动流程总能及时返回
+func (a *App) runStartWithTimeout(ctx context.Context) *StartError {
 	if a.startupTimeout > 0 {
-		ctx, cancel := context.WithTimeout(a.ctx, a.startupTimeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.startupTimeout)
 		defer cancel()
+	}
 
-		done := make(chan error, 1)
-		go func() {
-			done <- a.start(ctx) // 将带超时的 Context 传递给 Hook
-		}()
-
-		select {
-		case err := <-done:
-			return err
-		case <-ctx.Done():
-			return fmt.Errorf("app startup timed out after %v", a.startupTimeout)
-		}
+	err := a.start(ctx) // 将带超时的 Context 传递给 Hook
+	if err == nil {
+		return nil
+	}
+	var se *StartError
+	if !errors.As(err, &se) {
+		// 在钩子之间因超时或取消中止
+		se = &StartError{Phase: PhaseStart, Err: err}
 	}
-	return a.start(a.ctx)
+	if a.startupTimeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		se.Err = fmt.Errorf("%w after %v: %w", ErrStartupTimeout, a.startupTimeout, se.Err)
+	}
+	return se
 }
 
 func (a *App) start(ctx context.Context) error {
-	for i, hook := range a.hooks {
-		name := hook.Name
-		if name == "" {
-			name = fmt.Sprintf("hook#%d", i)
-		}
+	if a.parallel {
+		return a.startParallel(ctx)
+	}
 
+	for _, i := range a.order {
 		// 检查超时
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
+		if err := a.startHook(ctx, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		if hook.OnStart != nil {
-			a.log("Starting component...", "name", name)
-			start := time.Now()
-			if err := safeCall(ctx, hook.OnStart); err != nil {
-				return fmt.Errorf("failed to start [%s]: %w", name, err)
-			}
-			a.log("Started component", "name", name, "cost", formatCost(time.Since(start)))
+// startHook 启动单个钩子，成功后将其记录为已启动
+func (a *App) startHook(ctx context.Context, i int) error {
+	hook := a.hooks[i]
+	name := hookName(hook, i)
+
+	if hook.OnStart != nil {
+		a.debug("Starting component...", "hook", name, "phase", PhaseStart)
+		a.markHook(i, func(s *hookStatus) { s.state = HookStarting })
+		a.emitHook(EventHookStartBegin, i, PhaseStart, 0, nil)
+		start := time.Now()
+		hookCtx, span := a.startHookSpan(ctx, i, PhaseStart)
+		hookCtx = context.WithValue(hookCtx, hookKey{}, hookRef{app: a, index: i})
+		hookCtx, checkModule, cancel := a.moduleContext(hookCtx, i, PhaseStart)
+		err := checkModule(a.startWithRetry(hookCtx, i))
+		cancel()
+		if err != nil {
+			endSpan(span, err)
+			cost := time.Since(start)
+			a.markHook(i, func(s *hookStatus) { s.state, s.startCost, s.err = HookFailed, cost, err })
+			a.emitHook(EventHookStartEnd, i, PhaseStart, cost, err)
+			return startErrorFrom(newHookError(name, PhaseStart, cost, err))
 		}
+		endSpan(span, nil)
+		cost := time.Since(start)
+		a.markHook(i, func(s *hookStatus) { s.startCost = cost })
+		a.log("Started component", "hook", name, "phase", PhaseStart, "cost", formatCost(cost))
+		a.emitHook(EventHookStartEnd, i, PhaseStart, cost, nil)
+		a.checkUses(i)
+	}
 
-		a.mu.Lock()
-		a.hooks[i].OnStop = hook.OnStop // 确保 stop 逻辑存在
-		a.mu.Unlock()
+	a.mu.Lock()
+	a.started = append(a.started, i) // 只有已启动的组件才会被关闭
+	a.status[i].state = HookRunning
+	a.mu.Unlock()
+
+	if hook.Serve != nil {
+		a.serve(i)
 	}
 	return nil
 }
 
-func (a *App) stop(ctx context.Context) error {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+func (a *App) stop(ctx context.Context) (err error) {
+	begin := time.Now()
+	defer func() { a.emit(Event{Type: EventAppStopped, Duration: time.Since(begin), Err: err}) }()
+	ctx, span := a.startSpan(ctx, "crab.shutdown")
+	defer func() { endSpan(span, err) }()
+	a.closeServe()
 
-	var errs []error
-	for i := len(a.hooks) - 1; i >= 0; i-- {
-		hook := a.hooks[i]
-		name := hook.Name
-		if name == "" {
-			name = fmt.Sprintf("hook#%d", i)
+	// 等待被 closeServe 中止的重启结束；被监督者停止后未能重新启动的钩子已经关闭过，不再重复关闭。
+	// 关闭期间不持有 a.mu，避免阻塞 IsRunning 等调用
+	a.restartMu.Lock()
+	a.mu.Lock()
+	started := make([]int, 0, len(a.started))
+	for _, i := range a.started {
+		if !a.down[i] {
+			started = append(started, i)
 		}
+	}
+	a.mu.Unlock()
+	a.restartMu.Unlock()
 
-		if hook.OnStop != nil {
-			if ctx.Err() != nil {
-				return fmt.Errorf("shutdown aborted: %w", ctx.Err())
-			}
-
-			a.log("Stopping component...", "name", name)
-			start := time.Now()
-			if err := safeCall(ctx, func(c context.Context) error { return hook.OnStop(c) }); err != nil {
-				a.err("Failed to stop component", "name", name, "error", err)
-				errs = append(errs, fmt.Errorf("[%s] stop failed: %w", name, err))
-			} else {
-				a.log("Stopped component", "name", name, "cost", formatCost(time.Since(start)))
-			}
-		}
+	tracker := &shutdownTracker{}
+	if a.parallel {
+		a.stopParallel(ctx, started, tracker)
+	} else {
+		a.stopSequential(ctx, started, tracker)
 	}
 
+	a.mu.Lock()
+	// 没有 OnStop 与 Serve 的钩子无需关闭，直接标记为已关闭
+	for _, i := range started {
+		if !hasStop(a.hooks[i]) {
+			a.status[i].state = HookStopped
+		}
+	}
 	a.state = stateStopped
-	if len(errs) > 0 {
-		return fmt.Errorf("shutdown errors: %v", errs)
+	a.mu.Unlock()
+	if err := tracker.result(); err != nil {
+		return err
 	}
 	a.log("App stopped")
 	return nil
 }
 
-func safeCall(ctx context.Context, fn types.Runner) (err error) {
+// stopSequential 按启动完成顺序的逆序逐个关闭，即严格的依赖逆序
+func (a *App) stopSequential(ctx context.Context, started []int, t *shutdownTracker) {
+	for j := len(started) - 1; j >= 0; j-- {
+		if i := started[j]; hasStop(a.hooks[i]) {
+			a.stopOne(ctx, i, t)
+		}
+	}
+}
+
+// hasStop 判断钩子在关闭阶段是否有需要执行的动作
+func hasStop(h Hook) bool {
+	return h.OnStop != nil || h.Serve != nil
+}
+
+// stopHook 停止单个钩子：取消其 Serve、调用 OnStop 并等待 Serve 返回，
+// 整个过程受 StopTimeout 限制，超时后放弃该钩子
+func (a *App) stopHook(ctx context.Context, i int) (he *HookError) {
+	hook := a.hooks[i]
+	name := hookName(hook, i)
+	ctx, span := a.startHookSpan(ctx, i, PhaseStop)
 	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("panic recovered: %v\nstack: %s", r, debug.Stack())
+		if he != nil {
+			endSpan(span, he.Err)
+			return
 		}
+		endSpan(span, nil)
 	}()
-	return fn(ctx)
-}
+	parent := ctx
+	if hook.StopTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hook.StopTimeout)
+		defer cancel()
+	}
 
-func (a *App) log(msg string, args ...interface{}) {
-	if a.logger != nil {
-		a.logger.Info(a.ctx, msg, args...)
+	a.debug("Stopping component...", "hook", name, "phase", PhaseStop)
+	a.markHook(i, func(s *hookStatus) { s.state = HookStopping })
+	a.emitHook(EventHookStopBegin, i, PhaseStop, 0, nil)
+	start := time.Now()
+	fail := func(err error) *HookError {
+		err = timeoutError(parent, ctx, PhaseStop, hook.StopTimeout, err)
+		cost := time.Since(start)
+		a.markHook(i, func(s *hookStatus) { s.state, s.stopCost, s.err = HookFailed, cost, err })
+		a.emitHook(EventHookStopEnd, i, PhaseStop, cost, err)
+		return newHookError(name, PhaseStop, cost, err)
 	}
-}
 
-func (a *App) err(msg string, args ...interface{}) {
-	if a.logger != nil {
-		a.logger.Error(a.ctx, msg, args...)
+	handle := a.serveHandle(i)
+	if handle != nil {
+		handle.cancel()
+	}
+	if hook.OnStop != nil {
+		if err := a.callHook(ctx, i, PhaseStop, 0, types.Runner(hook.OnStop)); err != nil {
+			he = fail(err)
+			a.err("Failed to stop component", "hook", name, "phase", PhaseStop, "error", he.Err)
+			return he
+		}
+	}
+	if handle != nil {
+		if err := handle.wait(ctx); err != nil {
+			a.reportStuck(name, PhaseServe, err)
+			return fail(fmt.Errorf("serve did not exit: %w", err))
+		}
 	}
+	cost := time.Since(start)
+	a.markHook(i, func(s *hookStatus) { s.state, s.stopCost = HookStopped, cost })
+	a.log("Stopped component", "hook", name, "phase", PhaseStop, "cost", formatCost(cost))
+	a.emitHook(EventHookStopEnd, i, PhaseStop, cost, nil)
+	return nil
+}
+
+func safeCall(ctx context.Context, fn types.Runner) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
 }
 
 func (a *App) OnShutdown(fn func()) {
@@ -0,0 +1,634 @@
+package crab_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/bang-go/crab"
+	"github.com/bang-go/crab/crabtest"
+)
+
+var errBoom = errors.New("boom")
+
+// newApp 创建使用假信号来源、关闭内部日志的 App，并记录其生命周期事件
+func newApp(opts ...crab.Option) (*crab.App, *crabtest.Signals, *crabtest.Recorder) {
+	sig := crabtest.NewSignals()
+	rec := crabtest.NewRecorder()
+	opts = append([]crab.Option{
+		crab.WithLogger(nil),
+		crab.WithSignalNotifier(sig),
+		crab.WithObserver(rec.Observe),
+	}, opts...)
+	return crab.New(opts...), sig, rec
+}
+
+func TestStartStopOrder(t *testing.T) {
+	app, sig, rec := newApp()
+	// 按与依赖相反的顺序注册，顺序只由 DependsOn 决定
+	app.Add(
+		crabtest.Hook("api", "cache", "db"),
+		crabtest.Hook("cache", "db"),
+		crabtest.Hook("db"),
+	)
+
+	run := crabtest.Start(t, app)
+	rec.AssertStarted(t, "db", "cache", "api")
+	if !app.IsReady() {
+		t.Fatal("app is not ready after start")
+	}
+
+	if n := sig.Send(syscall.SIGTERM); n != 1 {
+		t.Fatalf("SIGTERM delivered to %d channels, want 1", n)
+	}
+	if err := run.Wait(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	rec.AssertStopped(t, "api", "cache", "db")
+}
+
+func TestRollback(t *testing.T) {
+	app, _, rec := newApp()
+	app.Add(
+		crabtest.Hook("db"),
+		crabtest.Fail("cache", crab.PhaseStop, errBoom),
+		crabtest.Fail("api", crab.PhaseStart, errBoom),
+		crabtest.Hook("worker", "api"),
+	)
+
+	err := app.Run()
+	var se *crab.StartError
+	if !errors.As(err, &se) {
+		t.Fatalf("Run() = %v, want *crab.StartError", err)
+	}
+	if se.Hook != "api" || se.Phase != crab.PhaseStart || !errors.Is(err, errBoom) {
+		t.Errorf("StartError = {Hook: %q, Phase: %q, Err: %v}, want api failing to start with %v", se.Hook, se.Phase, se.Err, errBoom)
+	}
+
+	// 只回滚已启动的组件，按启动的逆序；回滚中的关闭失败记录在 Rollback 中
+	rec.AssertStarted(t, "db", "cache")
+	rec.AssertStopped(t, "db")
+	var shutdown *crab.ShutdownError
+	if !errors.As(se.Rollback, &shutdown) {
+		t.Fatalf("Rollback = %v, want *crab.ShutdownError", se.Rollback)
+	}
+	if len(shutdown.Failed) != 1 || shutdown.Failed[0].Hook != "cache" {
+		t.Errorf("Rollback.Failed = %v, want [cache]", shutdown.Failed)
+	}
+	if !slices.Equal(shutdown.Completed, []string{"db"}) {
+		t.Errorf("Rollback.Completed = %q, want [db]", shutdown.Completed)
+	}
+}
+
+func TestStartupTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		app, _, rec := newApp(crab.WithStartupTimeout(5 * time.Second))
+		app.Add(
+			crabtest.Hook("db"),
+			crabtest.Hang("migrate", crab.PhaseStart, release),
+			crabtest.Hook("api", "migrate"),
+		)
+
+		begin := time.Now()
+		err := app.Run()
+		elapsed := time.Since(begin)
+		if !errors.Is(err, crab.ErrStartupTimeout) {
+			t.Fatalf("Run() = %v, want ErrStartupTimeout", err)
+		}
+		var se *crab.StartError
+		if !errors.As(err, &se) || se.Hook != "migrate" {
+			t.Errorf("Run() = %v, want migrate reported as the failed hook", err)
+		}
+		// 卡住的钩子在启动超时后被放弃，不会一直阻塞 Run
+		if elapsed < 5*time.Second || elapsed > 6*time.Second {
+			t.Errorf("Run() returned after %v, want just over the 5s startup timeout", elapsed)
+		}
+		rec.AssertStarted(t, "db")
+		rec.AssertStopped(t, "db")
+	})
+}
+
+func TestHookStartTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		app, _, rec := newApp()
+		app.Add(crab.Hook{
+			Name:         "dial",
+			StartTimeout: time.Second,
+			OnStart: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+
+		if err := app.Run(); !errors.Is(err, crab.ErrHookTimeout) {
+			t.Fatalf("Run() = %v, want ErrHookTimeout", err)
+		}
+		rec.AssertDuration(t, "dial", crab.PhaseStart, time.Second)
+	})
+}
+
+func TestSignalEscalation(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var exitCode int
+		app, sig, rec := newApp(crab.WithSignalPolicy(crab.SignalPolicy{
+			CriticalTimeout: 2 * time.Second,
+			Exit:            func(code int) { exitCode = code },
+		}))
+		app.Add(
+			crab.Hook{Name: "offsets", Critical: true, OnStop: func(context.Context) error { return nil }},
+			crabtest.Hook("cache", "offsets"),
+			crab.Hook{
+				Name:      "api",
+				DependsOn: []string{"cache"},
+				OnStop: func(ctx context.Context) error {
+					<-ctx.Done() // 优雅关闭很慢，直到被强制关闭取消
+					return ctx.Err()
+				},
+			},
+		)
+
+		run := crabtest.Start(t, app)
+		sig.Send(syscall.SIGTERM)
+		synctest.Wait() // 关闭流程阻塞在 api 的 OnStop 中
+		if app.IsReady() {
+			t.Error("app is still ready after SIGTERM")
+		}
+
+		sig.Send(syscall.SIGTERM)
+		err := run.Wait()
+		if !errors.Is(err, crab.ErrForcedShutdown) || !errors.Is(err, crab.ErrShutdownAborted) {
+			t.Fatalf("Run() = %v, want ErrForcedShutdown", err)
+		}
+		var se *crab.ShutdownError
+		if !errors.As(err, &se) || !slices.Equal(se.Skipped, []string{"cache"}) {
+			t.Errorf("Run() = %v, want only the non-critical cache skipped", err)
+		}
+		// 关键钩子在强制关闭时仍然关闭
+		rec.AssertStopped(t, "offsets")
+		if exitCode != 0 {
+			t.Errorf("exited with %d after two signals, want no exit", exitCode)
+		}
+
+		var forced bool
+		for _, e := range rec.Events() {
+			forced = forced || (e.Type == crab.EventForcedShutdown && e.Attempt == 2)
+		}
+		if !forced {
+			t.Error("no EventForcedShutdown recorded for the second signal")
+		}
+	})
+}
+
+func TestSignalForcedExit(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		exited := make(chan int, 1)
+		app, sig, _ := newApp(crab.WithSignalPolicy(crab.SignalPolicy{
+			ExitAfter: 3,
+			ExitCode:  7,
+			Exit:      func(code int) { exited <- code },
+		}))
+		// 关键钩子卡住时，第三个信号立即退出进程
+		hang := crabtest.Hang("offsets", crab.PhaseStop, release)
+		hang.Critical = true
+		app.Add(hang)
+
+		run := crabtest.Start(t, app)
+		for range 3 {
+			sig.Send(syscall.SIGTERM)
+			synctest.Wait()
+		}
+		select {
+		case code := <-exited:
+			if code != 7 {
+				t.Errorf("exit code = %d, want 7", code)
+			}
+		default:
+			t.Fatal("third signal did not exit the process")
+		}
+		_ = run.Wait() // 测试中 Exit 不会真正退出，关闭流程在强制关闭时限后结束
+	})
+}
+
+func TestSignalDuringHungReload(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		app, sig, rec := newApp()
+		app.Add(crabtest.Hook("db"), crabtest.Hang("config", crab.PhaseReload, release))
+
+		run := crabtest.Start(t, app)
+		if n := sig.Send(syscall.SIGHUP); n != 1 {
+			t.Fatalf("SIGHUP delivered to %d channels, want 1", n)
+		}
+		synctest.Wait() // 重载阻塞在 config 的 OnReload 中
+
+		// 卡住的重载不影响关闭信号的处理
+		begin := time.Now()
+		sig.Send(syscall.SIGTERM)
+		if err := run.Wait(); err != nil {
+			t.Fatalf("Run() = %v", err)
+		}
+		if elapsed := time.Since(begin); elapsed > time.Second {
+			t.Errorf("Run() returned %v after SIGTERM, want the hung reload abandoned", elapsed)
+		}
+		rec.AssertStopped(t, "config", "db")
+
+		var reloadErr error
+		for _, e := range rec.Events() {
+			if e.Type == crab.EventAppReloaded {
+				reloadErr = e.Err
+			}
+		}
+		if !errors.Is(reloadErr, context.Canceled) {
+			t.Errorf("reload result = %v, want it canceled by shutdown", reloadErr)
+		}
+	})
+}
+
+func TestReloadTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		var rolledBack bool
+		app, _, _ := newApp(crab.WithReloadTimeout(2 * time.Second))
+		app.Add(
+			crab.Hook{
+				Name:             "limiter",
+				OnReload:         func(context.Context) error { return nil },
+				OnReloadRollback: func(context.Context) error { rolledBack = true; return nil },
+			},
+			crabtest.Hang("config", crab.PhaseReload, release),
+		)
+		crabtest.Start(t, app)
+
+		begin := time.Now()
+		err := app.Reload(context.Background())
+		if !errors.Is(err, crab.ErrReloadTimeout) {
+			t.Fatalf("Reload() = %v, want ErrReloadTimeout", err)
+		}
+		if elapsed := time.Since(begin); elapsed < 2*time.Second || elapsed > 3*time.Second {
+			t.Errorf("Reload() returned after %v, want just over the 2s reload timeout", elapsed)
+		}
+		if !rolledBack {
+			t.Error("limiter was not rolled back after config timed out")
+		}
+		if !app.IsRunning() {
+			t.Error("app stopped after a failed reload")
+		}
+	})
+}
+
+func TestSkippedServeCanceled(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		serveDone := make(chan struct{})
+		app, _, _ := newApp(crab.WithShutdownTimeout(time.Second))
+		app.Add(
+			crab.Hook{
+				Name: "srv",
+				Serve: func(ctx context.Context) error {
+					defer close(serveDone)
+					<-ctx.Done()
+					return nil
+				},
+			},
+			// 后启动、先关闭，耗尽关闭时限后 srv 被跳过
+			crabtest.Hang("tracer", crab.PhaseStop, release),
+		)
+
+		err := crabtest.Start(t, app).Stop()
+		var se *crab.ShutdownError
+		if !errors.As(err, &se) || !slices.Contains(se.Skipped, "srv") {
+			t.Fatalf("Stop() = %v, want srv skipped", err)
+		}
+		synctest.Wait()
+		select {
+		case <-serveDone:
+		default:
+			t.Error("Serve of the skipped srv is still running after Run returned")
+		}
+	})
+}
+
+func TestStopDuringStartup(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var stops int
+		app, _, _ := newApp()
+		app.Add(
+			crab.Hook{Name: "db", OnStop: func(context.Context) error { stops++; return nil }},
+			crab.Hook{
+				Name:      "migrate",
+				DependsOn: []string{"db"},
+				OnStart: func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+		)
+
+		done := make(chan error, 1)
+		go func() { done <- app.Run() }()
+		synctest.Wait() // 启动阻塞在 migrate 的 OnStart 中
+
+		if err := app.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop() = %v", err)
+		}
+		var se *crab.StartError
+		if err := <-done; !errors.As(err, &se) || se.Hook != "migrate" {
+			t.Fatalf("Run() = %v, want migrate canceled during startup", err)
+		}
+		// Stop 与回滚不会重复关闭已启动的组件
+		if stops != 1 {
+			t.Errorf("db stopped %d times, want 1", stops)
+		}
+	})
+}
+
+func TestInspectAfterStop(t *testing.T) {
+	app, _, _ := newApp()
+	app.Add(
+		crab.Hook{Name: "config", OnStart: func(context.Context) error { return nil }},
+		crabtest.Hook("db", "config"),
+	)
+	if err := crabtest.Start(t, app).Stop(); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+	for _, info := range app.Inspect() {
+		if info.State != crab.HookStopped {
+			t.Errorf("hook %s is %s after the app stopped, want stopped", info.Name, info.State)
+		}
+	}
+}
+
+func TestHealthSkipsStoppedHooks(t *testing.T) {
+	app, _, _ := newApp(crab.WithHealthCacheTTL(0))
+	closed := false
+	var during crab.HealthReport
+	app.Add(
+		crabtest.Hook("db"),
+		crab.Hook{
+			Name:      "api",
+			DependsOn: []string{"db"},
+			Health: &crab.HealthCheck{
+				Critical: true,
+				Check: func(context.Context) error {
+					if closed {
+						return errors.New("api closed")
+					}
+					return nil
+				},
+			},
+			OnStop: func(ctx context.Context) error {
+				closed = true
+				during = app.Health(ctx) // 关闭中的组件不参与检查
+				return nil
+			},
+		},
+	)
+
+	run := crabtest.Start(t, app)
+	if report := app.Health(context.Background()); !report.Live || len(report.Components) != 1 {
+		t.Fatalf("Health() while running = %+v, want api up", report)
+	}
+	if err := run.Stop(); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+	if !during.Live || len(during.Components) != 0 {
+		t.Errorf("Health() during shutdown = %+v, want no checks against the stopping api", during)
+	}
+	if report := app.Health(context.Background()); !report.Live || len(report.Components) != 0 {
+		t.Errorf("Health() after shutdown = %+v, want no checks against stopped hooks", report)
+	}
+}
+
+// journal 按发生顺序记录钩子调用，供并发执行的钩子使用
+type journal struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (j *journal) add(entry string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, entry)
+}
+
+func (j *journal) get() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return slices.Clone(j.entries)
+}
+
+func TestRestartBudgetExceeded(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		app, _, rec := newApp()
+		app.Add(crab.Supervise("consumer", func(context.Context) error { return errBoom }, crab.RestartPolicy{
+			Mode:           crab.RestartOnFailure,
+			InitialBackoff: 100 * time.Millisecond,
+			MaxRestarts:    3,
+		}))
+
+		err := app.Run()
+		var he *crab.HookError
+		if !errors.As(err, &he) || he.Hook != "consumer" || he.Phase != crab.PhaseServe || !errors.Is(err, errBoom) {
+			t.Fatalf("Run() = %v, want consumer to exceed its restart budget", err)
+		}
+
+		var restarts []int
+		for _, e := range rec.Events() {
+			if e.Type == crab.EventHookRestart && e.Err == nil {
+				restarts = append(restarts, e.Attempt)
+			}
+		}
+		if !slices.Equal(restarts, []int{1, 2, 3}) {
+			t.Errorf("restart attempts = %v, want [1 2 3]", restarts)
+		}
+	})
+}
+
+func TestRestartOneForAll(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var j journal
+		hook := func(name string, deps ...string) crab.Hook {
+			return crab.Hook{
+				Name:      name,
+				DependsOn: deps,
+				OnStart:   func(context.Context) error { j.add(name + " start"); return nil },
+				OnStop:    func(context.Context) error { j.add(name + " stop"); return nil },
+			}
+		}
+
+		failed := false
+		db := hook("db")
+		db.Serve = func(ctx context.Context) error {
+			if !failed {
+				failed = true
+				return errBoom
+			}
+			<-ctx.Done()
+			return nil
+		}
+		db.Restart = &crab.RestartPolicy{Mode: crab.RestartOnFailure, Strategy: crab.OneForAll}
+
+		app, _, _ := newApp()
+		app.Add(db, hook("cache", "db"), hook("api", "cache"), hook("metrics"))
+		run := crabtest.Start(t, app)
+		time.Sleep(time.Second) // 退避结束，重启完成
+		synctest.Wait()
+
+		// 依赖 db 的组件按依赖逆序停止、按依赖顺序重新启动，无关的 metrics 不受影响
+		want := []string{
+			"db start", "cache start", "api start", "metrics start",
+			"api stop", "cache stop", "db stop",
+			"db start", "cache start", "api start",
+		}
+		if got := j.get(); !slices.Equal(got, want) {
+			t.Errorf("calls = %q,\nwant %q", got, want)
+		}
+		if err := run.Stop(); err != nil {
+			t.Fatalf("Stop() = %v", err)
+		}
+	})
+}
+
+func TestShutdownDuringRestartBackoff(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var starts, stops int
+		app, _, _ := newApp()
+		app.Add(crab.Hook{
+			Name:    "consumer",
+			OnStart: func(context.Context) error { starts++; return nil },
+			OnStop:  func(context.Context) error { stops++; return nil },
+			Serve:   func(context.Context) error { return errBoom },
+			Restart: &crab.RestartPolicy{Mode: crab.RestartOnFailure, InitialBackoff: 10 * time.Second},
+		})
+
+		run := crabtest.Start(t, app)
+		synctest.Wait() // Serve 已失败，监督者处于退避等待中
+
+		begin := time.Now()
+		if err := run.Stop(); err != nil {
+			t.Fatalf("Stop() = %v", err)
+		}
+		if elapsed := time.Since(begin); elapsed >= 10*time.Second {
+			t.Errorf("Stop() took %v, want the restart backoff abandoned", elapsed)
+		}
+		if starts != 1 || stops != 1 {
+			t.Errorf("OnStart called %d times and OnStop %d times, want 1 each", starts, stops)
+		}
+	})
+}
+
+func TestParallelMaxConcurrency(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		var active, peak int
+		app, _, rec := newApp(crab.WithParallelLifecycle(2))
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			app.Add(crab.Hook{Name: name, OnStart: func(context.Context) error {
+				mu.Lock()
+				active++
+				peak = max(peak, active)
+				mu.Unlock()
+				time.Sleep(time.Second)
+				mu.Lock()
+				active--
+				mu.Unlock()
+				return nil
+			}})
+		}
+
+		begin := time.Now()
+		if err := crabtest.Start(t, app).Stop(); err != nil {
+			t.Fatalf("Stop() = %v", err)
+		}
+		if peak != 2 {
+			t.Errorf("%d hooks started concurrently, want 2", peak)
+		}
+		if len(rec.Started()) != 5 {
+			t.Errorf("started %q, want all 5 hooks", rec.Started())
+		}
+		// 5 个钩子、每批 2 个，共 3 批
+		if elapsed := time.Since(begin); elapsed != 3*time.Second {
+			t.Errorf("startup took %v, want 3s", elapsed)
+		}
+	})
+}
+
+func TestParallelFailFast(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var slowErr error
+		app, _, rec := newApp(crab.WithParallelLifecycle(0))
+		app.Add(
+			crabtest.Hook("cache"),
+			crab.Hook{
+				Name: "db",
+				OnStart: func(context.Context) error {
+					time.Sleep(100 * time.Millisecond)
+					return errBoom
+				},
+				OnStop: func(context.Context) error { return nil },
+			},
+			crab.Hook{
+				Name: "search",
+				OnStart: func(ctx context.Context) error {
+					<-ctx.Done()
+					slowErr = ctx.Err()
+					return slowErr
+				},
+				OnStop: func(context.Context) error { return nil },
+			},
+			crabtest.Hook("api", "db", "search"),
+		)
+
+		begin := time.Now()
+		err := app.Run()
+		var se *crab.StartError
+		if !errors.As(err, &se) || se.Hook != "db" || !errors.Is(err, errBoom) {
+			t.Fatalf("Run() = %v, want db to fail the startup", err)
+		}
+		// db 失败后同层的 search 立即被取消，不必等到它自行结束
+		if !errors.Is(slowErr, context.Canceled) {
+			t.Errorf("search OnStart returned %v, want it canceled", slowErr)
+		}
+		if elapsed := time.Since(begin); elapsed != 100*time.Millisecond {
+			t.Errorf("Run() returned after %v, want 100ms", elapsed)
+		}
+		// 只有成功启动的 cache 被回滚，下一层的 api 没有启动
+		rec.AssertStarted(t, "cache")
+		rec.AssertStopped(t, "cache")
+	})
+}
+
+func TestParallelStopsScheduling(t *testing.T) {
+	var j journal
+	app, _, rec := newApp(crab.WithParallelLifecycle(1))
+	app.Add(crabtest.Fail("db", crab.PhaseStart, errBoom))
+	for _, name := range []string{"cache", "search"} {
+		app.Add(crab.Hook{Name: name, OnStart: func(context.Context) error { j.add(name); return nil }})
+	}
+
+	if err := app.Run(); !errors.Is(err, errBoom) {
+		t.Fatalf("Run() = %v, want %v", err, errBoom)
+	}
+	// 并发上限为 1 时 db 独自运行，失败后不再调度同层尚未开始的钩子
+	if got := j.get(); len(got) != 0 {
+		t.Errorf("started %q after db failed, want none", got)
+	}
+	rec.AssertStopped(t)
+}
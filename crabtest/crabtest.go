@@ -0,0 +1,137 @@
+// Package crabtest 提供确定性的生命周期测试工具：在后台运行 App 并等待启动完成、
+// 注入假的信号来源、记录启动/关闭顺序与耗时，以及构造失败、panic、卡死的钩子。
+// 不依赖真实信号和 sleep，可以在 testing/synctest 中使用，用虚拟时钟测试启动/关闭超时。
+//
+//	func TestShutdownOrder(t *testing.T) {
+//		sig := crabtest.NewSignals()
+//		rec := crabtest.NewRecorder()
+//		app := crab.New(crab.WithSignalNotifier(sig), crab.WithObserver(rec.Observe))
+//		app.Add(crabtest.Hook("db"), crabtest.Hook("api"))
+//
+//		run := crabtest.Start(t, app)
+//		sig.Send(syscall.SIGTERM)
+//		if err := run.Wait(); err != nil {
+//			t.Fatal(err)
+//		}
+//		rec.AssertStopped(t, "api", "db")
+//	}
+package crabtest
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/bang-go/crab"
+)
+
+// Running 是在后台运行的 App
+type Running struct {
+	App *crab.App
+
+	done chan struct{}
+	err  error
+}
+
+// Start 在后台调用 app.Run，阻塞到所有钩子启动完成；启动失败时调用 t.Fatal。
+// 测试结束时（t.Cleanup）如果 App 仍在运行则调用 Stop，此时 Run 返回错误会调用 t.Error
+func Start(t testing.TB, app *crab.App) *Running {
+	t.Helper()
+	started := make(chan struct{})
+	var once sync.Once
+	unsubscribe := app.Subscribe(func(e crab.Event) {
+		if e.Type == crab.EventAppStarted {
+			once.Do(func() { close(started) })
+		}
+	})
+
+	r := &Running{App: app, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		r.err = app.Run()
+	}()
+
+	select {
+	case <-started:
+		unsubscribe()
+	case <-r.done:
+		unsubscribe()
+		t.Fatalf("crabtest: app failed to start: %v", r.err)
+	}
+
+	t.Cleanup(func() {
+		select {
+		case <-r.done:
+			return // 测试中已经停止，Run 的结果由测试自行检查
+		default:
+		}
+		if err := r.Stop(); err != nil {
+			t.Errorf("crabtest: app stopped with error: %v", err)
+		}
+	})
+	return r
+}
+
+// Stop 调用 App.Stop 并等待 Run 返回，返回 Run 的结果；可以重复调用
+func (r *Running) Stop() error {
+	_ = r.App.Stop(context.Background())
+	return r.Wait()
+}
+
+// Wait 等待 Run 返回（例如投递信号或 Serve 退出之后），返回 Run 的结果
+func (r *Running) Wait() error {
+	<-r.done
+	return r.err
+}
+
+// Done 在 Run 返回后关闭
+func (r *Running) Done() <-chan struct{} {
+	return r.done
+}
+
+// Signals 是假的信号来源，通过 crab.WithSignalNotifier 注入后由 Send 按需投递信号
+type Signals struct {
+	mu    sync.Mutex
+	chans map[chan<- os.Signal][]os.Signal
+}
+
+// NewSignals 创建假的信号来源
+func NewSignals() *Signals {
+	return &Signals{chans: make(map[chan<- os.Signal][]os.Signal)}
+}
+
+// Notify 实现 crab.SignalNotifier
+func (s *Signals) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chans[c] = append(s.chans[c], sig...)
+}
+
+// Stop 实现 crab.SignalNotifier
+func (s *Signals) Stop(c chan<- os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.chans, c)
+}
+
+// Send 将 sig 投递给所有监听它的 channel，返回投递的数量；Start 返回后 App 已经开始监听。
+// 与 os/signal 一样不阻塞：channel 已满时丢弃该信号
+func (s *Signals) Send(sig os.Signal) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for c, sigs := range s.chans {
+		for _, want := range sigs {
+			if want == sig {
+				select {
+				case c <- sig:
+					n++
+				default:
+				}
+				break
+			}
+		}
+	}
+	return n
+}
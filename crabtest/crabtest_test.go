@@ -0,0 +1,148 @@
+package crabtest_test
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/bang-go/crab"
+	"github.com/bang-go/crab/crabtest"
+)
+
+func TestStartAndStop(t *testing.T) {
+	rec := crabtest.NewRecorder()
+	app := crab.New(crab.WithLogger(nil), crab.WithSignalNotifier(crabtest.NewSignals()), crab.WithObserver(rec.Observe))
+	app.Add(crabtest.Hook("db"), crabtest.Hook("api", "db"))
+
+	run := crabtest.Start(t, app)
+	if !app.IsRunning() {
+		t.Fatal("Start returned before the app was running")
+	}
+	if err := run.Stop(); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+	if err := run.Stop(); err != nil {
+		t.Fatalf("second Stop() = %v", err)
+	}
+	select {
+	case <-run.Done():
+	default:
+		t.Fatal("Done is not closed after Stop")
+	}
+	rec.AssertStarted(t, "db", "api")
+	rec.AssertStopped(t, "api", "db")
+}
+
+func TestStartCleanupStopsApp(t *testing.T) {
+	app := crab.New(crab.WithLogger(nil), crab.WithSignalNotifier(crabtest.NewSignals()))
+	app.Add(crabtest.Hook("db"))
+
+	var run *crabtest.Running
+	t.Run("running", func(t *testing.T) {
+		run = crabtest.Start(t, app)
+	})
+	select {
+	case <-run.Done():
+	default:
+		t.Fatal("app is still running after the test that started it finished")
+	}
+	if err := run.Wait(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+}
+
+func TestSignals(t *testing.T) {
+	sig := crabtest.NewSignals()
+	if n := sig.Send(syscall.SIGTERM); n != 0 {
+		t.Fatalf("Send() without listeners = %d, want 0", n)
+	}
+
+	c := make(chan os.Signal, 1)
+	sig.Notify(c, syscall.SIGTERM)
+	if n := sig.Send(syscall.SIGHUP); n != 0 {
+		t.Errorf("Send(SIGHUP) = %d, want 0 for a channel that only listens to SIGTERM", n)
+	}
+	if n := sig.Send(syscall.SIGTERM); n != 1 {
+		t.Errorf("Send(SIGTERM) = %d, want 1", n)
+	}
+	// channel 已满时与 os/signal 一样丢弃信号，不阻塞
+	if n := sig.Send(syscall.SIGTERM); n != 0 {
+		t.Errorf("Send() to a full channel = %d, want 0", n)
+	}
+	if got := <-c; got != syscall.SIGTERM {
+		t.Errorf("received %v, want SIGTERM", got)
+	}
+
+	sig.Stop(c)
+	if n := sig.Send(syscall.SIGTERM); n != 0 {
+		t.Errorf("Send() after Stop = %d, want 0", n)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	errBoom := errors.New("boom")
+	rec := crabtest.NewRecorder()
+	app := crab.New(crab.WithLogger(nil), crab.WithObserver(rec.Observe))
+	app.Add(
+		crabtest.Fail("cache", crab.PhaseStop, errBoom),
+		crabtest.Panic("api", crab.PhaseStart, "kaboom"),
+	)
+
+	if err := app.Run(); err == nil {
+		t.Fatal("Run() = nil, want the api panic")
+	}
+	var pe *crab.PanicError
+	if err := rec.Err("api", crab.PhaseStart); !errors.As(err, &pe) || pe.Value != "kaboom" {
+		t.Errorf("Err(api, start) = %v, want the recovered panic", err)
+	}
+	if err := rec.Err("cache", crab.PhaseStop); !errors.Is(err, errBoom) {
+		t.Errorf("Err(cache, stop) = %v, want %v", err, errBoom)
+	}
+	if _, ok := rec.Duration("cache", crab.PhaseStop); !ok {
+		t.Error("no stop duration recorded for cache")
+	}
+	if _, ok := rec.Duration("api", crab.PhaseStop); ok {
+		t.Error("stop duration recorded for api, which never started")
+	}
+}
+
+func TestUnsupportedPhase(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Fail with PhaseServe did not panic")
+		}
+	}()
+	crabtest.Fail("api", crab.PhaseServe, errors.New("boom"))
+}
+
+// 在 testing/synctest 中使用虚拟时钟：关闭超时瞬间完成，耗时断言精确
+func TestSynctest(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		rec := crabtest.NewRecorder()
+		app := crab.New(
+			crab.WithLogger(nil),
+			crab.WithSignalNotifier(crabtest.NewSignals()),
+			crab.WithObserver(rec.Observe),
+			crab.WithShutdownTimeout(10*time.Second),
+		)
+		hang := crabtest.Hang("tracer", crab.PhaseStop, release)
+		hang.StopTimeout = 2 * time.Second
+		app.Add(crabtest.Hook("db"), hang)
+
+		err := crabtest.Start(t, app).Stop()
+		if !errors.Is(err, crab.ErrHookTimeout) {
+			t.Fatalf("Stop() = %v, want ErrHookTimeout", err)
+		}
+		rec.AssertStopped(t, "db")
+		rec.AssertDuration(t, "tracer", crab.PhaseStop, 2*time.Second+100*time.Millisecond)
+		if d, _ := rec.Duration("tracer", crab.PhaseStop); d < 2*time.Second {
+			t.Errorf("tracer stop took %v, want at least its 2s StopTimeout", d)
+		}
+	})
+}
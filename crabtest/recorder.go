@@ -0,0 +1,160 @@
+package crabtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bang-go/crab"
+)
+
+// Recorder 记录生命周期事件，通过 crab.WithObserver(rec.Observe) 注册
+type Recorder struct {
+	mu     sync.Mutex
+	events []crab.Event
+}
+
+// NewRecorder 创建事件记录器
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Observe 记录一条事件
+func (r *Recorder) Observe(e crab.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// Events 返回已记录的所有事件
+func (r *Recorder) Events() []crab.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+// Started 返回成功执行 OnStart 的钩子，按完成顺序排列（没有 OnStart 的钩子不产生启动事件）
+func (r *Recorder) Started() []string {
+	return r.hooks(crab.EventHookStartEnd)
+}
+
+// Stopped 返回成功关闭的钩子，按完成顺序排列
+func (r *Recorder) Stopped() []string {
+	return r.hooks(crab.EventHookStopEnd)
+}
+
+func (r *Recorder) hooks(typ crab.EventType) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for _, e := range r.events {
+		if e.Type == typ && e.Err == nil {
+			names = append(names, e.Hook)
+		}
+	}
+	return names
+}
+
+// Duration 返回钩子最近一次启动（crab.PhaseStart）或关闭（crab.PhaseStop）的耗时，没有记录时返回 false
+func (r *Recorder) Duration(hook string, phase crab.Phase) (time.Duration, bool) {
+	typ := crab.EventHookStartEnd
+	if phase == crab.PhaseStop {
+		typ = crab.EventHookStopEnd
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range slices.Backward(r.events) {
+		if e.Type == typ && e.Hook == hook {
+			return e.Duration, true
+		}
+	}
+	return 0, false
+}
+
+// Err 返回钩子最近一次在 phase 阶段失败的错误
+func (r *Recorder) Err(hook string, phase crab.Phase) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range slices.Backward(r.events) {
+		if e.Hook == hook && e.Phase == phase && e.Err != nil {
+			return e.Err
+		}
+	}
+	return nil
+}
+
+// AssertStarted 断言成功启动的钩子及其顺序
+func (r *Recorder) AssertStarted(t testing.TB, want ...string) {
+	t.Helper()
+	if got := r.Started(); !slices.Equal(got, want) {
+		t.Errorf("crabtest: started %q, want %q", got, want)
+	}
+}
+
+// AssertStopped 断言成功关闭的钩子及其顺序
+func (r *Recorder) AssertStopped(t testing.TB, want ...string) {
+	t.Helper()
+	if got := r.Stopped(); !slices.Equal(got, want) {
+		t.Errorf("crabtest: stopped %q, want %q", got, want)
+	}
+}
+
+// AssertDuration 断言钩子在 phase 阶段的耗时不超过 max；在 testing/synctest 中耗时为虚拟时钟的时间
+func (r *Recorder) AssertDuration(t testing.TB, hook string, phase crab.Phase, max time.Duration) {
+	t.Helper()
+	d, ok := r.Duration(hook, phase)
+	switch {
+	case !ok:
+		t.Errorf("crabtest: no %s event recorded for hook [%s]", phase, hook)
+	case d > max:
+		t.Errorf("crabtest: hook [%s] %s took %v, want <= %v", hook, phase, d, max)
+	}
+}
+
+// Hook 返回一个什么也不做的钩子，用于观察启动/关闭顺序
+func Hook(name string, dependsOn ...string) crab.Hook {
+	return crab.Hook{
+		Name:      name,
+		DependsOn: dependsOn,
+		OnStart:   func(context.Context) error { return nil },
+		OnStop:    func(context.Context) error { return nil },
+	}
+}
+
+// Fail 返回在 phase 阶段（crab.PhaseStart、crab.PhaseStop 或 crab.PhaseReload）返回 err 的钩子
+func Fail(name string, phase crab.Phase, err error) crab.Hook {
+	return inject(name, phase, func(context.Context) error { return err })
+}
+
+// Panic 返回在 phase 阶段以 v panic 的钩子
+func Panic(name string, phase crab.Phase, v any) crab.Hook {
+	return inject(name, phase, func(context.Context) error { panic(v) })
+}
+
+// Hang 返回在 phase 阶段忽略 ctx、一直阻塞到 release 被关闭的钩子，用于测试超时与卡死诊断。
+// crab 放弃卡死的钩子后阻塞的 goroutine 仍然存在，测试结束前应关闭 release
+// （在 testing/synctest 中尤其如此，否则气泡无法结束）
+func Hang(name string, phase crab.Phase, release <-chan struct{}) crab.Hook {
+	return inject(name, phase, func(context.Context) error {
+		<-release
+		return nil
+	})
+}
+
+func inject(name string, phase crab.Phase, fn func(context.Context) error) crab.Hook {
+	h := Hook(name)
+	switch phase {
+	case crab.PhaseStart:
+		h.OnStart = fn
+	case crab.PhaseStop:
+		h.OnStop = fn
+	case crab.PhaseReload:
+		h.OnReload = fn
+	default:
+		panic(fmt.Sprintf("crabtest: unsupported phase %q", phase))
+	}
+	return h
+}
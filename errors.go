@@ -0,0 +1,181 @@
+package crab
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAlreadyStarted 重复调用 Run
+	ErrAlreadyStarted = errors.New("app already started")
+	// ErrNotRunning 应用未处于运行状态，例如在 Run 完成启动前或 Stop 之后调用 Reload
+	ErrNotRunning = errors.New("app is not running")
+	// ErrInvalidDependency Hook.DependsOn 引用了不存在或重名的钩子，或存在循环依赖
+	ErrInvalidDependency = errors.New("invalid hook dependency")
+	// ErrStartupTimeout 启动流程超过 WithStartupTimeout
+	ErrStartupTimeout = errors.New("app startup timed out")
+	// ErrReloadTimeout 重载超过 WithReloadTimeout
+	ErrReloadTimeout = errors.New("app reload timed out")
+	// ErrHookTimeout 钩子超过自身的 StartTimeout / StopTimeout
+	ErrHookTimeout = errors.New("hook timed out")
+	// ErrShutdownAborted 关闭流程超过 WithShutdownTimeout 而中止
+	ErrShutdownAborted = errors.New("shutdown aborted")
+	// ErrInvalidProvider Provide / Invoke 的函数签名不合法、缺少构造函数、重复提供同一类型或存在循环依赖
+	ErrInvalidProvider = errors.New("invalid provider")
+	// ErrComponentNotReady 在组件启动完成前或关闭后调用 Component.Get
+	ErrComponentNotReady = errors.New("component not ready")
+	// ErrGoroutineLeak 开启 WithLeakCheck 时，关闭完成后仍有 goroutine 在运行（见 *LeakError）
+	ErrGoroutineLeak = errors.New("goroutine leak")
+	// ErrForcedShutdown 关闭期间再次收到信号，跳过了非关键钩子
+	ErrForcedShutdown = errors.New("forced shutdown")
+)
+
+// Phase 表示钩子所处的生命周期阶段
+type Phase string
+
+const (
+	PhaseStart  Phase = "start"  // OnStart
+	PhaseStop   Phase = "stop"   // OnStop 及等待 Serve 退出
+	PhaseServe  Phase = "serve"  // Serve
+	PhaseReload Phase = "reload" // OnReload 及 OnReloadRollback
+)
+
+// PanicError 是钩子 panic 后由 crab 恢复得到的错误
+type PanicError struct {
+	Value any    // recover() 的返回值
+	Stack []byte // panic 时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic recovered: %v\nstack: %s", e.Value, e.Stack)
+}
+
+// HookError 描述单个钩子在某个阶段的失败
+type HookError struct {
+	Hook     string
+	Phase    Phase
+	Duration time.Duration // 该阶段的耗时
+	Panic    any           // 钩子 panic 时恢复的值，否则为 nil
+	Stack    []byte        // 钩子 panic 时的调用栈
+	Err      error
+}
+
+func newHookError(hook string, phase Phase, d time.Duration, err error) *HookError {
+	e := &HookError{Hook: hook, Phase: phase, Duration: d, Err: err}
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		e.Panic, e.Stack = pe.Value, pe.Stack
+	}
+	return e
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("[%s] %s failed: %v", e.Hook, e.Phase, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// StartError 是 Run 在启动失败时返回的错误
+type StartError struct {
+	Hook     string // 失败的钩子，启动因超时或取消在钩子之间中止时为空
+	Phase    Phase
+	Duration time.Duration
+	Panic    any
+	Stack    []byte
+	Err      error // 启动失败的原因
+	Rollback error // 回滚已启动组件时的错误，通常为 *ShutdownError
+}
+
+func (e *StartError) Error() string {
+	var b strings.Builder
+	if e.Hook != "" {
+		fmt.Fprintf(&b, "failed to start [%s]: %v", e.Hook, e.Err)
+	} else {
+		fmt.Fprintf(&b, "app start failed: %v", e.Err)
+	}
+	if e.Rollback != nil {
+		fmt.Fprintf(&b, " (rollback: %v)", e.Rollback)
+	}
+	return b.String()
+}
+
+// Unwrap 返回启动失败原因与回滚错误的 errors.Join 结果，使 errors.Is/As 可以访问两者
+func (e *StartError) Unwrap() error {
+	return errors.Join(e.Err, e.Rollback)
+}
+
+// startErrorFrom 将钩子的启动失败转换为 StartError
+func startErrorFrom(he *HookError) *StartError {
+	return &StartError{
+		Hook:     he.Hook,
+		Phase:    he.Phase,
+		Duration: he.Duration,
+		Panic:    he.Panic,
+		Stack:    he.Stack,
+		Err:      he.Err,
+	}
+}
+
+// ReloadError 是 Reload 失败时返回的错误
+type ReloadError struct {
+	Hook     string // 失败的钩子，重载因取消在钩子之间中止时为空
+	Err      error  // 重载失败的原因
+	Rollback error  // 调用 OnReloadRollback 时的错误
+}
+
+func (e *ReloadError) Error() string {
+	var b strings.Builder
+	if e.Hook != "" {
+		fmt.Fprintf(&b, "failed to reload [%s]: %v", e.Hook, e.Err)
+	} else {
+		fmt.Fprintf(&b, "app reload failed: %v", e.Err)
+	}
+	if e.Rollback != nil {
+		fmt.Fprintf(&b, " (rollback: %v)", e.Rollback)
+	}
+	return b.String()
+}
+
+// Unwrap 返回重载失败原因与回滚错误的 errors.Join 结果
+func (e *ReloadError) Unwrap() error {
+	return errors.Join(e.Err, e.Rollback)
+}
+
+// ShutdownError 是关闭流程中有钩子失败或被跳过时返回的错误，
+// 分别列出成功关闭、关闭失败和被跳过的组件（均按关闭完成的顺序排列）
+type ShutdownError struct {
+	Completed []string     // 成功关闭的钩子
+	Failed    []*HookError // 关闭失败的钩子
+	Skipped   []string     // 关闭期限到期后未被调用的钩子（ShutdownAbort 模式）
+	Aborted   error        // 关闭流程中止的原因（包装 ErrShutdownAborted），未中止时为 nil
+}
+
+func (e *ShutdownError) Error() string {
+	msgs := make([]string, 0, len(e.Failed)+1)
+	if e.Aborted != nil {
+		msgs = append(msgs, e.Aborted.Error())
+	}
+	failed := make([]string, len(e.Failed))
+	for k, f := range e.Failed {
+		msgs = append(msgs, f.Error())
+		failed[k] = f.Hook
+	}
+	return fmt.Sprintf("shutdown errors: %s (completed: %v; failed: %v; skipped: %v)",
+		strings.Join(msgs, "; "), e.Completed, failed, e.Skipped)
+}
+
+// Unwrap 返回所有失败的 errors.Join 结果，使 errors.Is/As 可以访问各组件自身的错误
+func (e *ShutdownError) Unwrap() error {
+	errs := make([]error, 0, len(e.Failed)+1)
+	if e.Aborted != nil {
+		errs = append(errs, e.Aborted)
+	}
+	for _, f := range e.Failed {
+		errs = append(errs, f)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package crab
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// 信号升级策略的默认值
+const (
+	defaultCriticalTimeout = 3 * time.Second
+	defaultExitAfter       = 3
+	defaultForcedExitCode  = 3
+)
+
+// SignalPolicy 描述关闭开始后再次收到信号时的升级策略：
+// 第二个信号跳过剩余的优雅关闭步骤，只在 CriticalTimeout 内关闭标记为 Hook.Critical 的钩子；
+// 第 ExitAfter 个信号以 ExitCode 立即退出进程
+type SignalPolicy struct {
+	CriticalTimeout time.Duration  // 强制关闭时关键钩子的总时限，默认 3s
+	ExitAfter       int            // 收到第几个信号时立即退出，默认 3，最小为 2
+	ExitCode        int            // 立即退出时的退出码，默认 3，用于和普通失败区分
+	Exit            func(code int) // 退出函数，默认 os.Exit，可在测试中替换
+}
+
+func (p SignalPolicy) withDefaults() SignalPolicy {
+	if p.CriticalTimeout <= 0 {
+		p.CriticalTimeout = defaultCriticalTimeout
+	}
+	if p.ExitAfter <= 0 {
+		p.ExitAfter = defaultExitAfter
+	}
+	p.ExitAfter = max(p.ExitAfter, 2)
+	if p.ExitCode == 0 {
+		p.ExitCode = defaultForcedExitCode
+	}
+	if p.Exit == nil {
+		p.Exit = os.Exit
+	}
+	return p
+}
+
+// WithSignalPolicy 设置关闭期间重复收到信号时的升级策略
+func WithSignalPolicy(p SignalPolicy) Option {
+	return func(a *App) {
+		a.signalPolicy = p.withDefaults()
+	}
+}
+
+// watchSignals 在关闭流程中继续监听关闭信号并逐级升级，返回停止监听的函数。
+// 关闭流程开始本身计为第一步，此后每个信号升级一步
+func (a *App) watchSignals(c <-chan os.Signal) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		count := 1
+		for {
+			select {
+			case sig := <-c:
+				count++
+				a.emit(Event{Type: EventSignal, Signal: sig, Attempt: count})
+				if count >= a.signalPolicy.ExitAfter {
+					a.err("Received signal again, exiting immediately", "signal", sig, "count", count, "exit_code", a.signalPolicy.ExitCode)
+					a.emit(Event{Type: EventForcedExit, Signal: sig, Attempt: count})
+					a.signalPolicy.Exit(a.signalPolicy.ExitCode)
+					return
+				}
+				a.escalate(sig, count)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// escalate 进入强制关闭：中止优雅关闭（包括排空等待），此后只关闭关键钩子
+func (a *App) escalate(sig os.Signal, count int) {
+	a.mu.Lock()
+	if a.forceCtx != nil {
+		a.mu.Unlock()
+		return
+	}
+	a.forceCtx, a.forceCancel = context.WithTimeout(context.Background(), a.signalPolicy.CriticalTimeout)
+	cancelStop := a.stopCancel
+	close(a.forced)
+	a.mu.Unlock()
+
+	a.err("Received signal again, forcing shutdown: only critical hooks will be stopped",
+		"signal", sig, "count", count, "timeout", formatCost(a.signalPolicy.CriticalTimeout))
+	a.emit(Event{Type: EventForcedShutdown, Signal: sig, Attempt: count, Duration: a.signalPolicy.CriticalTimeout})
+	if cancelStop != nil {
+		cancelStop(ErrForcedShutdown)
+	}
+}
+
+// forcedContext 返回强制关闭时关键钩子共用的 Context，未进入强制关闭时返回 nil
+func (a *App) forcedContext() context.Context {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.forceCtx
+}
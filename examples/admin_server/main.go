@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bang-go/crab"
+	"github.com/bang-go/crab/admin"
+)
+
+func main() {
+	// 内置管理端：无需再复制 K8S 探针、pprof 等路由
+	//   curl localhost:9090/readyz
+	//   curl localhost:9090/healthz
+	//   curl localhost:9090/lifecycle
+	//   curl -X POST -H "Authorization: Bearer secret" localhost:9090/shutdown
+	app := crab.New()
+	if _, err := admin.Listen(app, ":9090", admin.WithShutdownToken("secret")); err != nil {
+		log.Fatalf("管理端启动失败: %v", err)
+	}
+
+	app.Add(crab.Hook{
+		Name: "database",
+		OnStart: func(ctx context.Context) error {
+			time.Sleep(200 * time.Millisecond) // 模拟建立连接
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return nil
+		},
+		Health: &crab.HealthCheck{
+			Critical: true,
+			Check: func(ctx context.Context) error {
+				return nil // 例如 db.PingContext(ctx)
+			},
+		},
+	})
+
+	app.Add(crab.Hook{
+		Name:      "worker",
+		DependsOn: []string{"database"},
+		Serve: func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		},
+	})
+
+	if err := app.Run(); err != nil {
+		log.Printf("应用错误: %v", err)
+	}
+}
@@ -40,6 +40,7 @@ func main() {
 	// 1. 注册配置加载 Hook
 	// ----------------------------------------------------------------
 	app.Add(crab.Hook{
+		Name: "config",
 		OnStart: func(ctx context.Context) error {
 			fmt.Println("\n--- [Step 1] 开始加载配置 ---")
 			// 模拟加载配置
@@ -61,6 +62,8 @@ func main() {
 	wrongDB := NewDatabase(wrongDSN)
 
 	app.Add(crab.Hook{
+		Name:      "wrong-db",
+		DependsOn: []string{"config"}, // 声明依赖也无济于事：值在 Add 时就已经读取了
 		OnStart: func(ctx context.Context) error {
 			fmt.Println("--- [Step 2] 启动错误的数据库连接 ---")
 			fmt.Printf("❌ 错误连接: DSN = %q (预期是 mysql://...)\n", wrongDB.DSN)
@@ -77,15 +80,49 @@ func main() {
 	fmt.Println("--- [Setup] 准备注册正确的 Hook ---")
 
 	app.Add(crab.Hook{
+		Name:      "right-db",
+		DependsOn: []string{"config"}, // 声明依赖，保证配置 Hook 先于本 Hook 启动
 		OnStart: func(ctx context.Context) error {
 			fmt.Println("--- [Step 3] 启动正确的数据库连接 ---")
 
 			// ✅ 正确：这里是在 Run() -> start() 流程中执行的
-			// 此时 Step 1 已经执行完毕，配置已加载
+			// DependsOn 保证此时 Step 1 已经执行完毕，配置已加载
 			realDSN := ViperGetString("db.dsn")
 
 			realDB := NewDatabase(realDSN)
 			fmt.Printf("✅ 正确连接: DSN = %q\n", realDB.DSN)
+			return nil
+		},
+	})
+
+	// ----------------------------------------------------------------
+	// 4. 推荐写法：使用 crab.Component 让值只能在启动完成后取得
+	// ----------------------------------------------------------------
+	reg := crab.NewRegistry()
+	conf := crab.NewComponent(reg, "typed-config", func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"db.dsn": fakeViperData["db.dsn"]}, nil
+	}, nil, "config")
+
+	// ❌ 在启动前读取会直接得到错误，而不是悄悄拿到空值
+	if _, err := conf.Get(); err != nil {
+		fmt.Printf("⚠️ [Setup] %v\n", err)
+	}
+
+	db := crab.NewComponent(reg, "typed-db", func(ctx context.Context) (*Database, error) {
+		// ✅ GetContext 会记录 typed-db 使用了 typed-config；忘记声明依赖时 crab 会在启动阶段报错或告警
+		c, err := conf.GetContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return NewDatabase(c["db.dsn"]), nil
+	}, nil, "typed-config")
+	app.Add(reg.Hooks()...)
+
+	app.Add(crab.Hook{
+		Name:      "business",
+		DependsOn: []string{"typed-db"},
+		OnStart: func(ctx context.Context) error {
+			fmt.Printf("✅ [Step 4] 类型安全的连接: DSN = %q\n", db.MustGetContext(ctx).DSN)
 
 			// 演示完成，退出
 			go func() {
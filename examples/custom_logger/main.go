@@ -216,7 +216,9 @@ func argsToAttrs(args []any) []slog.Attr {
 // =============================================================================
 
 // 现在 crab.Logger 接口已经兼容了您的 Info/Error 方法签名
-// 无需任何适配器或包装器！
+// 无需任何适配器或包装器！Logger 同时实现了 Debug/Warn，
+// crab 会自动识别 (crab.LeveledLogger)，逐个组件的启动/关闭过程以 Debug 级别输出。
+// 如果直接使用 *slog.Logger，只需 crab.WithSlog(logger)
 
 func main() {
 	// 1. 初始化您的 Micro Logger
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -40,26 +41,22 @@ func main() {
 
 			server = &http.Server{Addr: ":8080", Handler: mux}
 			log.Println("启动 HTTP 服务器", "addr", ":8080")
-
-			// 使用 goroutine 启动服务器，因为 ListenAndServe 是阻塞的
-			go func() {
-				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-					log.Printf("HTTP 服务器错误: %v", err)
-				}
-			}()
-
-			// 简单的等待一下确保 goroutine 启动
-			time.Sleep(100 * time.Millisecond)
 			return nil
 		},
-		OnStop: func(ctx context.Context) error {
-			if server != nil {
-				log.Println("优雅关闭 HTTP 服务器")
-				// 使用传入的 ctx（已经包含了 ShutdownTimeout）
-				return server.Shutdown(ctx)
+		// Serve 由 crab 在受管 goroutine 中运行：
+		// 如果服务器意外退出（例如端口被占用），应用会自动优雅关闭，app.Run 返回该错误
+		Serve: func(ctx context.Context) error {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
 			}
 			return nil
 		},
+		OnStop: func(ctx context.Context) error {
+			log.Println("优雅关闭 HTTP 服务器")
+			// 使用传入的 ctx（已经包含了 ShutdownTimeout）
+			// Shutdown 返回后 ListenAndServe 退出，crab 会等待 Serve 返回
+			return server.Shutdown(ctx)
+		},
 	})
 
 	// Run - 运行应用
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -16,7 +18,9 @@ func main() {
 
 	app := crab.New()
 
+	var server *http.Server
 	app.Add(crab.Hook{
+		Name: "http-server",
 		OnStart: func(ctx context.Context) error {
 			// 创建一个 ServeMux (模拟业务路由)
 			mux := http.NewServeMux()
@@ -27,43 +31,63 @@ func main() {
 			})
 
 			// 2. K8S Liveness Probe (存活检测)
-			// 只要进程在，通常就返回 200。或者检查死锁等致命错误。
+			// 只有关键 (Critical) 健康检查失败时才返回 503，触发容器重启
 			mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte("ok"))
+				if app.Health(r.Context()).Live {
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte("ok"))
+				} else {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					w.Write([]byte("unhealthy"))
+				}
 			})
 
 			// 3. K8S Readiness Probe (就绪检测)
-			// 关键点：这里调用 app.IsRunning() 来判断应用是否完全启动
-			// 只有当 app.Run() 中的所有 OnStart 钩子都执行完毕，状态才会变为 Running
+			// 关键点：只有当 app.Run() 中的所有 OnStart 钩子都执行完毕（应用处于 Running 状态）
+			// 且所有组件的健康检查都通过时，Ready 才为 true
 			mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
-				if app.IsRunning() {
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte("ready"))
-				} else {
-					// 还在启动中，或者正在关闭中
+				report := app.Health(r.Context())
+				w.Header().Set("Content-Type", "application/json")
+				if !report.Ready {
+					// 还在启动中、正在关闭中，或者有组件不健康
 					w.WriteHeader(http.StatusServiceUnavailable)
-					w.Write([]byte("not ready"))
 				}
+				json.NewEncoder(w).Encode(report)
 			})
 
-			server := &http.Server{Addr: ":8080", Handler: mux}
-
-			// 启动服务
-			go func() {
-				fmt.Println("[Server] Listening on :8080")
-				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-					log.Printf("Server Error: %v", err)
-				}
-			}()
+			server = &http.Server{Addr: ":8080", Handler: mux}
+			return nil
+		},
+		// 启动服务：由 crab 跟踪，服务异常退出会让应用优雅关闭，Pod 不会"假活"
+		Serve: func(ctx context.Context) error {
+			fmt.Println("[Server] Listening on :8080")
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return server.Shutdown(ctx)
+		},
+	})
 
-			// 模拟一个耗时的启动过程，方便观察 /readyz 的状态变化
+	// 模拟一个耗时的启动过程，方便观察 /readyz 的状态变化
+	app.Add(crab.Hook{
+		Name:      "cache",
+		DependsOn: []string{"http-server"},
+		OnStart: func(ctx context.Context) error {
 			fmt.Println("[Init] 正在预热缓存 (3秒)...")
 			time.Sleep(3 * time.Second)
 			fmt.Println("[Init] 预热完成")
-
 			return nil
 		},
+		// 非关键检查：失败时只摘除流量 (readiness)，不会重启容器 (liveness)
+		Health: &crab.HealthCheck{
+			Timeout: time.Second,
+			Check: func(ctx context.Context) error {
+				return nil // 例如 redis.Ping(ctx)
+			},
+		},
 	})
 
 	// 运行应用
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bang-go/crab"
+	"github.com/bang-go/crab/metrics"
+)
+
+func main() {
+	// 1. 创建指标收集器，并作为观察者注册到 App
+	m := metrics.New()
+	app := crab.New(crab.WithObserver(m.Observe))
+
+	// 2. 挂载到业务自己的 mux 上
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	srv := &http.Server{Addr: ":9090", Handler: mux}
+
+	app.Add(crab.Hook{
+		Name: "database",
+		OnStart: func(ctx context.Context) error {
+			time.Sleep(120 * time.Millisecond) // 模拟建立连接
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			time.Sleep(30 * time.Millisecond)
+			return nil
+		},
+	})
+	app.Add(crab.Hook{
+		Name:      "metrics-server",
+		DependsOn: []string{"database"},
+		Serve: func(ctx context.Context) error {
+			go func() {
+				<-ctx.Done()
+				_ = srv.Shutdown(context.Background())
+			}()
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	})
+
+	// 模拟运行一会后退出，退出前输出一次指标
+	go func() {
+		time.Sleep(time.Second)
+		_ = m.Write(os.Stdout)
+		_ = app.Stop(context.Background())
+	}()
+
+	// curl http://localhost:9090/metrics
+	if err := app.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
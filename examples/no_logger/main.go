@@ -9,8 +9,9 @@ import (
 )
 
 func main() {
-	// 默认不配置 Logger
-	app := crab.New()
+	// 默认输出到 stderr（级别由 CRAB_LOG_LEVEL 控制，off 关闭）；
+	// 显式传入 nil 可以完全关闭 crab 的内部日志
+	app := crab.New(crab.WithLogger(nil))
 
 	app.Add(crab.Hook{
 		Name: "SilentComponent",
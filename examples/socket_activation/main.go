@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/bang-go/crab"
+	"github.com/bang-go/crab/listen"
+)
+
+// 配合 systemd 使用：
+//
+//	# myapp.socket
+//	[Socket]
+//	ListenStream=8080
+//	FileDescriptorName=http
+//
+// 由 systemd 启动时使用继承的套接字（重启期间连接由内核排队，不会被拒绝）；
+// 直接运行时自行监听 :8080
+func main() {
+	// 启动完成后关闭未被认领的继承套接字
+	app := crab.New(crab.WithObserver(listen.Observe))
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	})}
+
+	// 必须在 OnStart 中认领继承的套接字：listen.Observe 在启动完成时关闭未被认领的套接字，
+	// 而 Serve 运行在独立的 goroutine 中，可能晚于这一时刻
+	var ln net.Listener
+	app.Add(crab.Hook{
+		Name: "http",
+		OnStart: func(ctx context.Context) error {
+			var err error
+			ln, err = listen.Listen("http", "tcp", ":8080")
+			return err
+		},
+		Serve: func(ctx context.Context) error {
+			go func() {
+				<-ctx.Done()
+				_ = srv.Shutdown(context.Background())
+			}()
+			if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	})
+
+	if err := app.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
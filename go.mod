@@ -2,9 +2,9 @@ module github.com/bang-go/crab
 
 go 1.25.5
 
-require go.opentelemetry.io/otel/trace v1.39.0
-
 require (
-	github.com/cespare/xxhash/v2 v2.3.0 // indirect
-	go.opentelemetry.io/otel v1.39.0 // indirect
+	go.opentelemetry.io/otel v1.39.0
+	go.opentelemetry.io/otel/trace v1.39.0
 )
+
+require github.com/cespare/xxhash/v2 v2.3.0 // indirect
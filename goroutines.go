@@ -0,0 +1,108 @@
+package crab
+
+import (
+	"bufio"
+	"bytes"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+)
+
+// 钩子 goroutine 的 pprof 标签，钩子内部创建的 goroutine 会继承这些标签
+const (
+	labelApp   = "crab_app"
+	labelHook  = "crab_hook"
+	labelPhase = "crab_phase"
+)
+
+// goroutineGroup 是 goroutine profile (debug=1) 中调用栈与标签都相同的一组 goroutine
+type goroutineGroup struct {
+	count  int
+	labels string // "{"k":"v", ...}"，没有标签时为空
+	stack  string // 每行一个栈帧
+}
+
+// hasLabel 判断该组 goroutine 是否带有指定的 pprof 标签
+func (g goroutineGroup) hasLabel(key, value string) bool {
+	return strings.Contains(g.labels, strconv.Quote(key)+":"+strconv.Quote(value))
+}
+
+// label 返回该组 goroutine 的 pprof 标签值，没有该标签时返回空字符串
+func (g goroutineGroup) label(key string) string {
+	_, rest, ok := strings.Cut(g.labels, strconv.Quote(key)+":")
+	if !ok {
+		return ""
+	}
+	quoted, err := strconv.QuotedPrefix(rest)
+	if err != nil {
+		return ""
+	}
+	value, _ := strconv.Unquote(quoted)
+	return value
+}
+
+// String 返回可读的调用栈
+func (g goroutineGroup) String() string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(g.count))
+	b.WriteString(" goroutine(s)")
+	if g.labels != "" {
+		b.WriteString(" ")
+		b.WriteString(g.labels)
+	}
+	b.WriteString(":\n")
+	b.WriteString(g.stack)
+	return b.String()
+}
+
+// goroutineGroups 解析当前进程的 goroutine profile
+func goroutineGroups() []goroutineGroup {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+
+	var groups []goroutineGroup
+	var cur *goroutineGroup
+	sc := bufio.NewScanner(&buf)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			cur = nil
+		case cur == nil:
+			// "N @ 0x... 0x..." 开始一个新的分组
+			n, _, ok := strings.Cut(line, " @ ")
+			if !ok {
+				continue
+			}
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				continue
+			}
+			groups = append(groups, goroutineGroup{count: count})
+			cur = &groups[len(groups)-1]
+		case strings.HasPrefix(line, "# labels: "):
+			cur.labels = strings.TrimPrefix(line, "# labels: ")
+		case strings.HasPrefix(line, "#\t"):
+			// "#\t0x4e143c\tmain.main.func1+0x1c\t/tmp/main.go:12"
+			fields := strings.Fields(strings.TrimPrefix(line, "#\t"))
+			if len(fields) >= 3 {
+				cur.stack += "\t" + fields[1] + " " + fields[len(fields)-1] + "\n"
+			}
+		}
+	}
+	return groups
+}
+
+// hookStacks 返回指定应用中某个钩子（及其创建的）goroutine 的调用栈
+func hookStacks(appID, name string) string {
+	var b strings.Builder
+	for _, g := range goroutineGroups() {
+		if g.hasLabel(labelApp, appID) && g.hasLabel(labelHook, name) {
+			b.WriteString(g.String())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,166 @@
+package crab
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHealthTimeout  = 5 * time.Second
+	defaultHealthCacheTTL = time.Second
+)
+
+// HealthCheck 定义组件的健康检查
+type HealthCheck struct {
+	Check    func(ctx context.Context) error
+	Timeout  time.Duration // 单次检查的超时时间，默认 5s
+	Critical bool          // 关键检查：失败时影响存活探针 (liveness)；否则只影响就绪探针 (readiness)
+}
+
+// HealthStatus 表示健康状态
+type HealthStatus string
+
+const (
+	HealthUp       HealthStatus = "up"       // 所有检查通过
+	HealthDegraded HealthStatus = "degraded" // 仅非关键检查失败
+	HealthDown     HealthStatus = "down"     // 存在失败的关键检查
+)
+
+// ComponentHealth 单个组件的健康检查结果
+type ComponentHealth struct {
+	Name        string        `json:"name"`
+	Status      HealthStatus  `json:"status"`
+	Critical    bool          `json:"critical"`
+	Error       string        `json:"error,omitempty"`        // 本次检查的错误
+	LastError   string        `json:"last_error,omitempty"`   // 最近一次失败的错误，恢复后仍保留
+	LastErrorAt time.Time     `json:"last_error_at,omitzero"` // 最近一次失败的时间
+	CheckedAt   time.Time     `json:"checked_at,omitzero"`    // 本次检查的时间
+	Duration    time.Duration `json:"duration_ns,omitempty"`  // 本次检查的耗时
+}
+
+// HealthReport 应用的聚合健康状态
+type HealthReport struct {
+	Status     HealthStatus      `json:"status"`
+	Live       bool              `json:"live"`  // 没有失败的关键检查
+	Ready      bool              `json:"ready"` // 应用处于运行状态、未在排空流量且所有检查通过
+	Components []ComponentHealth `json:"components"`
+}
+
+// healthEntry 缓存单个组件的检查结果
+type healthEntry struct {
+	result    ComponentHealth
+	expiresAt time.Time
+}
+
+// WithHealthCacheTTL 设置健康检查结果的缓存时间，默认 1s，<= 0 表示每次都重新检查
+func WithHealthCacheTTL(d time.Duration) Option {
+	return func(a *App) {
+		a.healthCacheTTL = d
+	}
+}
+
+// Health 并发执行运行中组件的健康检查（结果在缓存时间内复用），返回聚合状态。
+// 尚未启动、正在关闭或已关闭（包括被监督者停止等待重启）的组件不参与检查，
+// 应用未处于运行状态或正在排空流量（见 WithDrainDelay）时 Ready 为 false
+func (a *App) Health(ctx context.Context) HealthReport {
+	a.mu.Lock()
+	running := a.state == stateRunning && !a.draining
+	started := make([]int, 0, len(a.started))
+	for _, i := range a.started {
+		if a.status[i].state == HookRunning && !a.down[i] {
+			started = append(started, i)
+		}
+	}
+	a.mu.Unlock()
+
+	a.healthMu.Lock()
+	defer a.healthMu.Unlock()
+
+	now := time.Now()
+	fresh := make([]*ComponentHealth, len(started))
+	var wg sync.WaitGroup
+	for k, i := range started {
+		check := a.hooks[i].Health
+		if check == nil || check.Check == nil {
+			continue
+		}
+		if e := a.health[i]; e != nil && now.Before(e.expiresAt) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := a.checkHealth(ctx, i, check)
+			fresh[k] = &result
+		}()
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: HealthUp, Live: true, Ready: running, Components: []ComponentHealth{}}
+	for k, i := range started {
+		if result := fresh[k]; result != nil {
+			if prev := a.health[i]; prev != nil && result.LastError == "" {
+				result.LastError, result.LastErrorAt = prev.result.LastError, prev.result.LastErrorAt
+			}
+			a.health[i] = &healthEntry{result: *result, expiresAt: result.CheckedAt.Add(a.healthCacheTTL)}
+		}
+		e := a.health[i]
+		if e == nil {
+			continue
+		}
+
+		report.Components = append(report.Components, e.result)
+		if e.result.Status == HealthUp {
+			continue
+		}
+		report.Ready = false
+		if e.result.Critical {
+			report.Live = false
+			report.Status = HealthDown
+		} else if report.Status == HealthUp {
+			report.Status = HealthDegraded
+		}
+	}
+	return report
+}
+
+// checkHealth 在超时限制内执行一次健康检查，即使检查函数忽略 ctx 也不会阻塞调用方
+func (a *App) checkHealth(ctx context.Context, i int, check *HealthCheck) ComponentHealth {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- safeCall(ctx, check.Check)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("health check did not finish: %w", ctx.Err())
+	}
+
+	result := ComponentHealth{
+		Name:      hookName(a.hooks[i], i),
+		Status:    HealthUp,
+		Critical:  check.Critical,
+		CheckedAt: start,
+		Duration:  time.Since(start),
+	}
+	if err != nil {
+		result.Status = HealthDown
+		result.Error = err.Error()
+		result.LastError = result.Error
+		result.LastErrorAt = start
+		a.err("Health check failed", "hook", result.Name, "critical", check.Critical, "error", err)
+	}
+	return result
+}
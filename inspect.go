@@ -0,0 +1,82 @@
+package crab
+
+import "time"
+
+// HookState 表示钩子在生命周期中的状态
+type HookState string
+
+const (
+	HookPending  HookState = "pending"  // 尚未启动
+	HookStarting HookState = "starting" // OnStart 执行中
+	HookRunning  HookState = "running"  // 启动完成
+	HookStopping HookState = "stopping" // 关闭中
+	HookStopped  HookState = "stopped"  // 已关闭
+	HookFailed   HookState = "failed"   // 启动或关闭失败
+)
+
+// HookInfo 描述钩子当前的生命周期状态，用于运行时自省
+type HookInfo struct {
+	Name      string        `json:"name"`
+	DependsOn []string      `json:"depends_on,omitempty"`
+	Source    string        `json:"source,omitempty"` // 注册钩子的位置
+	State     HookState     `json:"state"`
+	StartCost time.Duration `json:"start_cost_ns,omitempty"` // OnStart 耗时
+	StopCost  time.Duration `json:"stop_cost_ns,omitempty"`  // 关闭耗时
+	Error     string        `json:"error,omitempty"`         // 最近一次启动或关闭失败的错误
+	Uses      []string      `json:"uses,omitempty"`          // 启动时通过 Component.GetContext 使用的组件
+}
+
+// hookStatus 记录钩子的运行时状态，由 a.mu 保护
+type hookStatus struct {
+	state     HookState
+	startCost time.Duration
+	stopCost  time.Duration
+	err       error
+	uses      []int // 启动时使用的组件所在的钩子下标
+}
+
+// Inspect 返回所有钩子的生命周期状态；Run 解析依赖后按启动顺序排列，之前按 Add 顺序排列
+func (a *App) Inspect() []HookInfo {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	order := a.order
+	if order == nil {
+		order = make([]int, len(a.hooks))
+		for i := range order {
+			order[i] = i
+		}
+	}
+
+	infos := make([]HookInfo, 0, len(order))
+	for _, i := range order {
+		info := HookInfo{
+			Name:      hookName(a.hooks[i], i),
+			DependsOn: a.hooks[i].DependsOn,
+			Source:    a.hooks[i].Source,
+			State:     HookPending,
+		}
+		if i < len(a.status) {
+			st := a.status[i]
+			info.State = st.state
+			info.StartCost, info.StopCost = st.startCost, st.stopCost
+			if st.err != nil {
+				info.Error = st.err.Error()
+			}
+			for _, j := range st.uses {
+				info.Uses = append(info.Uses, hookName(a.hooks[j], j))
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// markHook 在 a.mu 保护下更新钩子的运行时状态
+func (a *App) markHook(i int, update func(*hookStatus)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if i < len(a.status) {
+		update(&a.status[i])
+	}
+}
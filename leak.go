@@ -0,0 +1,231 @@
+package crab
+
+import (
+	"bufio"
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 关闭完成后等待 goroutine 自行退出的时间与轮询间隔：Shutdown 返回后连接等 goroutine 往往还需要片刻才能结束
+const (
+	leakSettle       = time.Second
+	leakPollInterval = 20 * time.Millisecond
+)
+
+// WithLeakCheck 开启 goroutine 泄漏检查：Run 开始前记录已有的 goroutine，最后一个 OnStop 完成后
+// 仍在运行的新 goroutine 视为泄漏，按创建函数分组、附带调用栈通过 Logger 输出，并让 Run 返回 *LeakError。
+// 由钩子的 OnStart（或 Serve 等其他阶段）创建的 goroutine 会尽可能归属到该钩子。
+// 进程内其他与应用无关的 goroutine 也可能被报告，建议只在测试或预发环境开启
+func WithLeakCheck() Option {
+	return func(a *App) {
+		a.leakCheck = true
+	}
+}
+
+// LeakedGoroutines 是一组创建位置相同的泄漏 goroutine
+type LeakedGoroutines struct {
+	Hook      string // 创建这些 goroutine 的钩子，无法归属时为空
+	Phase     Phase  // 创建这些 goroutine 时钩子所处的阶段
+	CreatedBy string // 创建这些 goroutine 的函数
+	Count     int
+	Stack     string // 其中一个 goroutine 的调用栈
+}
+
+// LeakError 表示关闭完成后仍有 goroutine 在运行
+type LeakError struct {
+	Leaks []LeakedGoroutines
+}
+
+func (e *LeakError) Error() string {
+	total := 0
+	parts := make([]string, len(e.Leaks))
+	for k, l := range e.Leaks {
+		total += l.Count
+		parts[k] = fmt.Sprintf("%d created by %s", l.Count, l.CreatedBy)
+		if l.Hook != "" {
+			parts[k] = fmt.Sprintf("[%s] %s", l.Hook, parts[k])
+		}
+	}
+	return fmt.Sprintf("%v: %d goroutine(s) still running after shutdown (%s)", ErrGoroutineLeak, total, strings.Join(parts, "; "))
+}
+
+func (e *LeakError) Is(target error) bool {
+	return target == ErrGoroutineLeak
+}
+
+// goroutine 是 runtime.Stack 输出中的一个 goroutine
+type goroutine struct {
+	id        int
+	createdBy string
+	stack     string // runtime.Stack 中的原始调用栈
+	frames    string // 归一化后的栈帧，用于与 goroutine profile 匹配
+}
+
+// checkLeaks 等待新 goroutine 退出，超过 leakSettle 后报告仍在运行的 goroutine
+func (a *App) checkLeaks(baseline map[int]bool) error {
+	self := currentGoroutineID()
+	var survivors []goroutine
+	for deadline := time.Now().Add(leakSettle); ; {
+		survivors = survivors[:0]
+		for _, g := range goroutines() {
+			if !baseline[g.id] && g.id != self && !ignoredGoroutine(g) {
+				survivors = append(survivors, g)
+			}
+		}
+		if len(survivors) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(leakPollInterval)
+	}
+
+	// 钩子 goroutine 带有 pprof 标签，其创建的 goroutine 会继承；按归一化的栈帧匹配找回标签
+	type owner struct {
+		hook  string
+		phase Phase
+	}
+	owners := make(map[string]owner)
+	for _, g := range goroutineGroups() {
+		if !g.hasLabel(labelApp, a.id) {
+			continue
+		}
+		sig := normalizeProfileStack(g.stack)
+		if _, ok := owners[sig]; !ok {
+			owners[sig] = owner{hook: g.label(labelHook), phase: Phase(g.label(labelPhase))}
+		}
+	}
+
+	var leaks []LeakedGoroutines
+	index := make(map[string]int)
+	for _, g := range survivors {
+		o := owners[g.frames]
+		key := o.hook + "\x00" + g.createdBy
+		if k, ok := index[key]; ok {
+			leaks[k].Count++
+			continue
+		}
+		index[key] = len(leaks)
+		leaks = append(leaks, LeakedGoroutines{Hook: o.hook, Phase: o.phase, CreatedBy: g.createdBy, Count: 1, Stack: g.stack})
+	}
+	slices.SortStableFunc(leaks, func(x, y LeakedGoroutines) int { return y.Count - x.Count })
+
+	for _, l := range leaks {
+		a.err("Goroutine leak detected", "hook", l.Hook, "phase", l.Phase, "created_by", l.CreatedBy, "count", l.Count, "goroutines", l.Stack)
+	}
+	return &LeakError{Leaks: leaks}
+}
+
+// goroutineIDs 返回当前所有 goroutine 的 ID
+func goroutineIDs() map[int]bool {
+	ids := make(map[int]bool)
+	for _, g := range goroutines() {
+		ids[g.id] = true
+	}
+	return ids
+}
+
+// goroutines 解析 runtime.Stack 输出的所有 goroutine
+func goroutines() []goroutine {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	var result []goroutine
+	for _, block := range strings.Split(string(buf), "\n\n") {
+		sc := bufio.NewScanner(strings.NewReader(block))
+		sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		if !sc.Scan() {
+			continue
+		}
+		// "goroutine 7 [chan receive]:"
+		head := strings.Fields(sc.Text())
+		if len(head) < 2 || head[0] != "goroutine" {
+			continue
+		}
+		id, err := strconv.Atoi(head[1])
+		if err != nil {
+			continue
+		}
+		g := goroutine{id: id, stack: block}
+		var frames strings.Builder
+		for sc.Scan() {
+			fn := sc.Text()
+			if strings.HasPrefix(fn, "...") {
+				continue // "...additional frames elided..."
+			}
+			if !sc.Scan() {
+				break
+			}
+			file, _, _ := strings.Cut(strings.TrimSpace(sc.Text()), " ")
+			if by, ok := strings.CutPrefix(fn, "created by "); ok {
+				g.createdBy, _, _ = strings.Cut(by, " in goroutine ")
+				continue
+			}
+			// "pkg.(*T).Method(0xc000..., ...)" -> "pkg.(*T).Method"
+			if k := strings.LastIndex(fn, "("); k > 0 && strings.HasSuffix(fn, ")") {
+				fn = fn[:k]
+			}
+			writeFrame(&frames, fn, file)
+		}
+		g.frames = frames.String()
+		result = append(result, g)
+	}
+	return result
+}
+
+// normalizeProfileStack 将 goroutineGroup.stack（"\tfunc+0x1c file:line"）归一化为与 goroutines 相同的格式
+func normalizeProfileStack(stack string) string {
+	var frames strings.Builder
+	for line := range strings.Lines(stack) {
+		fn, file, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if k := strings.LastIndex(fn, "+0x"); k > 0 {
+			fn = fn[:k]
+		}
+		writeFrame(&frames, fn, file)
+	}
+	return frames.String()
+}
+
+// writeFrame 写入一个栈帧；runtime.Stack 默认不包含运行时内部的栈帧，两种格式都跳过它们
+func writeFrame(b *strings.Builder, fn, file string) {
+	if strings.HasPrefix(fn, "runtime.") {
+		return
+	}
+	b.WriteString(fn)
+	b.WriteByte(' ')
+	b.WriteString(file)
+	b.WriteByte('\n')
+}
+
+// ignoredGoroutine 判断是否为标准库按需启动、常驻整个进程的 goroutine
+func ignoredGoroutine(g goroutine) bool {
+	return strings.Contains(g.stack, "os/signal.loop") || strings.Contains(g.stack, "os/signal.signal_recv")
+}
+
+// currentGoroutineID 返回当前 goroutine 的 ID
+func currentGoroutineID() int {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// "goroutine 7 [running]:..."
+	fields := strings.Fields(string(buf))
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.Atoi(fields[1])
+	return id
+}
@@ -1,6 +1,10 @@
 package crab
 
-import "sync"
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
 
 // Lifecycle defines the interface for managing application lifecycle hooks.
 // Providers should depend on this interface to register their startup/shutdown logic
@@ -21,6 +25,9 @@ type Lifecycle interface {
 	Append(Hook)
 }
 
+// ErrDuplicateHook is returned by Registry.Merge when two registries contain hooks with the same name.
+var ErrDuplicateHook = errors.New("duplicate hook")
+
 // Registry is a thread-safe implementation of Lifecycle.
 // It is designed to be used as a singleton in the dependency injection container.
 type Registry struct {
@@ -36,7 +43,10 @@ func NewRegistry() *Registry {
 }
 
 // Append registers a new hook safely.
+// The caller's function and file:line are recorded in Hook.Source (unless already set),
+// which is used as the hook's name in logs and errors when Name is empty.
 func (r *Registry) Append(h Hook) {
+	h = withSource(h, 1)
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.hooks = append(r.hooks, h)
@@ -52,3 +62,80 @@ func (r *Registry) Hooks() []Hook {
 	copy(result, r.hooks)
 	return result
 }
+
+// Scope returns a child Lifecycle that registers hooks into r with names prefixed by "prefix/".
+// It is a shorthand for crab.Scope(r, prefix).
+func (r *Registry) Scope(prefix string) Lifecycle {
+	return Scope(r, prefix)
+}
+
+// Merge appends the hooks of the given registries (typically collected by sub-modules) to r.
+// If a hook name appears more than once across r and others, nothing is merged and an error
+// wrapping ErrDuplicateHook reports where each duplicate was registered.
+func (r *Registry) Merge(others ...*Registry) error {
+	var incoming []Hook
+	for _, o := range others {
+		if o != r {
+			incoming = append(incoming, o.Hooks()...)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]Hook, len(r.hooks)+len(incoming))
+	var errs []error
+	for _, h := range append(r.hooks[:len(r.hooks):len(r.hooks)], incoming...) {
+		if h.Name == "" {
+			continue
+		}
+		if prev, ok := seen[h.Name]; ok {
+			errs = append(errs, fmt.Errorf("%w: [%s] registered at %s and %s", ErrDuplicateHook, h.Name, sourceOrUnknown(prev), sourceOrUnknown(h)))
+			continue
+		}
+		seen[h.Name] = h
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	r.hooks = append(r.hooks, incoming...)
+	return nil
+}
+
+func sourceOrUnknown(h Hook) string {
+	if h.Source == "" {
+		return "unknown location"
+	}
+	return h.Source
+}
+
+// Scope returns a Lifecycle that prefixes the names of hooks appended through it with "prefix/"
+// before forwarding them to lc. Unnamed hooks stay unnamed (so several of them never collide in
+// Registry.Merge); their Source is prefixed instead, which is how they show up in logs and errors.
+// Scopes can be nested, e.g. crab.Scope(reg.Scope("storage"), "redis").
+// DependsOn is not rewritten: refer to hooks in the same scope by their full name,
+// or use crab.Module when dependencies should resolve within the group.
+//
+//	func NewStorage(lc crab.Lifecycle) *Storage {
+//	    lc = crab.Scope(lc, "storage")
+//	    lc.Append(crab.Hook{Name: "mysql", ...}) // registered as "storage/mysql"
+//	    ...
+//	}
+func Scope(lc Lifecycle, prefix string) Lifecycle {
+	return &scope{parent: lc, prefix: prefix}
+}
+
+type scope struct {
+	parent Lifecycle
+	prefix string
+}
+
+func (s *scope) Append(h Hook) {
+	h = withSource(h, 1)
+	switch {
+	case h.Name != "":
+		h.Name = s.prefix + "/" + h.Name
+	case h.Source != "":
+		h.Source = s.prefix + "/" + h.Source
+	}
+	s.parent.Append(h)
+}
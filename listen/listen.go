@@ -0,0 +1,202 @@
+// Package listen 支持 systemd socket activation：解析 LISTEN_FDS / LISTEN_PID / LISTEN_FDNAMES，
+// 按名称分发继承的监听套接字，没有继承时自行监听地址。
+//
+//	app := crab.New(crab.WithObserver(listen.Observe)) // 启动完成后关闭未被认领的继承套接字
+//	app.Add(crab.Hook{
+//		Name: "http",
+//		OnStart: func(ctx context.Context) error {
+//			ln, err := listen.Listen("http", "tcp", ":8080")
+//			...
+//		},
+//	})
+package listen
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bang-go/crab"
+)
+
+// listenFdsStart 是 systemd 传递的第一个文件描述符 (SD_LISTEN_FDS_START)
+const listenFdsStart = 3
+
+// socket 是一个继承的套接字，流式套接字为 listener，数据报套接字为 packet
+type socket struct {
+	name     string
+	listener net.Listener
+	packet   net.PacketConn
+}
+
+func (s *socket) addr() net.Addr {
+	if s.listener != nil {
+		return s.listener.Addr()
+	}
+	return s.packet.LocalAddr()
+}
+
+func (s *socket) close() error {
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return s.packet.Close()
+}
+
+var (
+	once     sync.Once
+	mu       sync.Mutex
+	sockets  []*socket // 尚未被认领的继承套接字
+	parseErr error
+)
+
+// inherited 在第一次调用时解析环境变量并接管继承的文件描述符。
+// 解析后清除这些环境变量，避免被子进程误继承
+func inherited() error {
+	once.Do(func() {
+		sockets, parseErr = parse(os.Getpid(), os.Getenv)
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	})
+	return parseErr
+}
+
+// parse 按 sd_listen_fds(3) 的约定解析继承的套接字，LISTEN_PID 不是当前进程时忽略
+func parse(pid int, getenv func(string) string) ([]*socket, error) {
+	if getenv("LISTEN_PID") != strconv.Itoa(pid) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+
+	var names []string
+	if v := getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	result := make([]*socket, 0, n)
+	var errs []error
+	for k := range n {
+		fd := listenFdsStart + k
+		name := ""
+		if k < len(names) {
+			name = names[k]
+		}
+
+		// FileListener / FilePacketConn 会复制文件描述符，原描述符随后关闭
+		f := os.NewFile(uintptr(fd), name)
+		s := &socket{name: name}
+		if s.listener, err = net.FileListener(f); err != nil {
+			s.packet, err = net.FilePacketConn(f)
+		}
+		_ = f.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("inherited fd %d (%s): %w", fd, name, err))
+			continue
+		}
+		result = append(result, s)
+	}
+	return result, errors.Join(errs...)
+}
+
+// claim 取出第一个名称匹配、或名称为空但地址匹配的继承套接字
+func claim(name, network, addr string, match func(*socket) bool) *socket {
+	mu.Lock()
+	defer mu.Unlock()
+	pick := func(ok func(*socket) bool) *socket {
+		for k, s := range sockets {
+			if match(s) && ok(s) {
+				sockets = append(sockets[:k:k], sockets[k+1:]...)
+				return s
+			}
+		}
+		return nil
+	}
+	if s := pick(func(s *socket) bool { return name != "" && s.name == name }); s != nil {
+		return s
+	}
+	return pick(func(s *socket) bool { return sameAddr(s.addr(), network, addr) })
+}
+
+// Listen 返回名为 name 的继承监听套接字；没有同名套接字时尝试匹配地址相同的继承套接字，
+// 仍然没有时调用 net.Listen(network, addr) 自行监听
+func Listen(name, network, addr string) (net.Listener, error) {
+	if err := inherited(); err != nil {
+		return nil, err
+	}
+	if s := claim(name, network, addr, func(s *socket) bool { return s.listener != nil }); s != nil {
+		return s.listener, nil
+	}
+	return net.Listen(network, addr)
+}
+
+// ListenPacket 与 Listen 相同，用于 UDP 等数据报套接字
+func ListenPacket(name, network, addr string) (net.PacketConn, error) {
+	if err := inherited(); err != nil {
+		return nil, err
+	}
+	if s := claim(name, network, addr, func(s *socket) bool { return s.packet != nil }); s != nil {
+		return s.packet, nil
+	}
+	return net.ListenPacket(network, addr)
+}
+
+// Names 返回尚未被认领的继承套接字名称
+func Names() []string {
+	_ = inherited()
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, len(sockets))
+	for k, s := range sockets {
+		names[k] = s.name
+	}
+	return names
+}
+
+// CloseUnclaimed 关闭所有未被认领的继承套接字，避免 systemd 认为服务仍在处理这些连接
+func CloseUnclaimed() error {
+	_ = inherited()
+	mu.Lock()
+	unclaimed := sockets
+	sockets = nil
+	mu.Unlock()
+
+	var errs []error
+	for _, s := range unclaimed {
+		if err := s.close(); err != nil {
+			errs = append(errs, fmt.Errorf("close inherited socket %q: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Observe 在应用启动完成（或启动失败回滚结束）后关闭未被认领的继承套接字，
+// 通过 crab.WithObserver(listen.Observe) 注册
+func Observe(e crab.Event) {
+	switch e.Type {
+	case crab.EventAppStarted, crab.EventAppStopped:
+		_ = CloseUnclaimed()
+	}
+}
+
+// sameAddr 判断继承套接字的地址是否与 network/addr 相同；addr 未指定 IP 时只比较端口
+func sameAddr(got net.Addr, network, addr string) bool {
+	switch g := got.(type) {
+	case *net.TCPAddr:
+		want, err := net.ResolveTCPAddr(network, addr)
+		return err == nil && g.Port == want.Port && (want.IP == nil || want.IP.IsUnspecified() || want.IP.Equal(g.IP))
+	case *net.UDPAddr:
+		want, err := net.ResolveUDPAddr(network, addr)
+		return err == nil && g.Port == want.Port && (want.IP == nil || want.IP.IsUnspecified() || want.IP.Equal(g.IP))
+	case *net.UnixAddr:
+		return strings.HasPrefix(network, "unix") && g.Name == addr
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+//go:build unix
+
+package listen
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// 子进程模式下由父进程通过环境变量传入的继承套接字地址
+const (
+	envChild    = "CRAB_LISTEN_TEST_CHILD"
+	envHTTPAddr = "CRAB_LISTEN_TEST_HTTP"
+	envDNSAddr  = "CRAB_LISTEN_TEST_DNS"
+	envIdleAddr = "CRAB_LISTEN_TEST_IDLE"
+)
+
+// TestInherited 模拟 systemd socket activation：父进程通过 cmd.ExtraFiles 将套接字传给
+// 重新执行的测试二进制（从 fd 3 开始），子进程按名称和地址认领，并关闭未被认领的套接字
+func TestInherited(t *testing.T) {
+	if os.Getenv(envChild) == "1" {
+		testInheritedChild(t)
+		return
+	}
+
+	httpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpLn.Close()
+	dns, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dns.Close()
+	idleLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idleLn.Close()
+
+	var files []*os.File
+	for _, s := range []interface{ File() (*os.File, error) }{
+		httpLn.(*net.TCPListener), dns.(*net.UDPConn), idleLn.(*net.TCPListener),
+	} {
+		f, err := s.File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files = append(files, f)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestInherited$", "-test.v")
+	cmd.Env = append(os.Environ(),
+		envChild+"=1",
+		envHTTPAddr+"="+httpLn.Addr().String(),
+		envDNSAddr+"="+dns.LocalAddr().String(),
+		envIdleAddr+"="+idleLn.Addr().String(),
+		"LISTEN_FDS=3",
+		// dns 没有名称，需要按地址匹配
+		"LISTEN_FDNAMES=http::idle",
+	)
+	cmd.ExtraFiles = files
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child process failed: %v\n%s", err, out)
+	}
+}
+
+func testInheritedChild(t *testing.T) {
+	// 父进程无法预知子进程的 PID，由子进程自己补上
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	if got, want := Names(), []string{"http", "", "idle"}; !slices.Equal(got, want) {
+		t.Fatalf("Names() = %q, want %q", got, want)
+	}
+	if v := os.Getenv("LISTEN_FDS"); v != "" {
+		t.Errorf("LISTEN_FDS = %q after parsing, want it unset", v)
+	}
+
+	// 按名称认领：地址与继承的套接字不同也应该取得继承的套接字
+	ln, err := Listen("http", "tcp", "127.0.0.1:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if got, want := ln.Addr().String(), os.Getenv(envHTTPAddr); got != want {
+		t.Errorf("Listen(http) addr = %s, want inherited %s", got, want)
+	}
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	accepted, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted.Close()
+
+	// 按地址认领未命名的套接字
+	pc, err := ListenPacket("dns", "udp", os.Getenv(envDNSAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	if got, want := pc.LocalAddr().String(), os.Getenv(envDNSAddr); got != want {
+		t.Errorf("ListenPacket(dns) addr = %s, want inherited %s", got, want)
+	}
+
+	if got, want := Names(), []string{"idle"}; !slices.Equal(got, want) {
+		t.Fatalf("Names() after claiming = %q, want %q", got, want)
+	}
+	if err := CloseUnclaimed(); err != nil {
+		t.Fatal(err)
+	}
+	if got := Names(); len(got) != 0 {
+		t.Errorf("Names() after CloseUnclaimed = %q, want none", got)
+	}
+
+	// 已被关闭的继承套接字不会再被认领，回退为自行监听
+	fresh, err := Listen("idle", "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Close()
+	if fresh.Addr().String() == os.Getenv(envIdleAddr) {
+		t.Errorf("Listen(idle) returned the closed inherited socket %s", fresh.Addr())
+	}
+}
+
+func TestParseIgnoresOtherProcess(t *testing.T) {
+	env := map[string]string{"LISTEN_PID": "1", "LISTEN_FDS": "1"}
+	sockets, err := parse(os.Getpid(), func(k string) string { return env[k] })
+	if err != nil || sockets != nil {
+		t.Fatalf("parse() = %v, %v; want nothing for another process", sockets, err)
+	}
+}
+
+func TestSameAddr(t *testing.T) {
+	tcp := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}
+	tests := []struct {
+		network, addr string
+		want          bool
+	}{
+		{"tcp", ":8080", true},
+		{"tcp", "0.0.0.0:8080", true},
+		{"tcp", "127.0.0.1:8080", true},
+		{"tcp", "10.0.0.1:8080", false},
+		{"tcp", ":8081", false},
+	}
+	for _, tt := range tests {
+		if got := sameAddr(tcp, tt.network, tt.addr); got != tt.want {
+			t.Errorf("sameAddr(%s, %s %s) = %v, want %v", tcp, tt.network, tt.addr, got, tt.want)
+		}
+	}
+}
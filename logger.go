@@ -0,0 +1,191 @@
+package crab
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// LogLevelEnv 是控制默认日志级别的环境变量，取值 debug / info / warn / error / off，默认 info
+const LogLevelEnv = "CRAB_LOG_LEVEL"
+
+// LeveledLogger 是支持 Debug / Warn 级别的 Logger。
+// WithLogger 传入的实现带有 Debug 或 Warn 方法时会被自动使用；
+// 否则 Debug 日志降级为 Info，Warn 日志升级为 Error
+type LeveledLogger interface {
+	Logger
+	Debug(ctx context.Context, msg string, args ...interface{})
+	Warn(ctx context.Context, msg string, args ...interface{})
+}
+
+// WithSlog 使用 *slog.Logger 输出日志
+func WithSlog(l *slog.Logger) Option {
+	return func(a *App) {
+		a.logger = FromSlog(l)
+	}
+}
+
+// FromSlog 将 *slog.Logger 适配为 LeveledLogger
+func FromSlog(l *slog.Logger) LeveledLogger {
+	return slogLogger{l: l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Debug(ctx context.Context, msg string, args ...interface{}) {
+	s.l.DebugContext(ctx, msg, args...)
+}
+
+func (s slogLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	s.l.InfoContext(ctx, msg, args...)
+}
+
+func (s slogLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	s.l.WarnContext(ctx, msg, args...)
+}
+
+func (s slogLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	s.l.ErrorContext(ctx, msg, args...)
+}
+
+// ToSlog 将 Logger 适配为 *slog.Logger，便于业务代码与 crab 共用同一个日志实现
+func ToSlog(l Logger) *slog.Logger {
+	if s, ok := l.(slogLogger); ok {
+		return s.l
+	}
+	return slog.New(&loggerHandler{l: l})
+}
+
+// loggerHandler 是把日志记录转发给 Logger 的 slog.Handler
+type loggerHandler struct {
+	l      Logger
+	attrs  []interface{}
+	prefix string // WithGroup 累积的键名前缀
+}
+
+func (h *loggerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if level < slog.LevelInfo {
+		_, ok := h.l.(interface {
+			Debug(ctx context.Context, msg string, args ...interface{})
+		})
+		return ok
+	}
+	return true
+}
+
+func (h *loggerHandler) Handle(ctx context.Context, r slog.Record) error {
+	args := append([]interface{}(nil), h.attrs...)
+	r.Attrs(func(attr slog.Attr) bool {
+		args = appendAttr(args, h.prefix, attr)
+		return true
+	})
+	logAt(ctx, h.l, r.Level, r.Message, args...)
+	return nil
+}
+
+func (h *loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]interface{}(nil), h.attrs...)
+	for _, attr := range attrs {
+		next.attrs = appendAttr(next.attrs, h.prefix, attr)
+	}
+	return &next
+}
+
+func (h *loggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+// appendAttr 将 attr 展开为键值对追加到 args，分组以 "group.key" 的形式展开
+func appendAttr(args []interface{}, prefix string, attr slog.Attr) []interface{} {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return args
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range attr.Value.Group() {
+			args = appendAttr(args, prefix, a)
+		}
+		return args
+	}
+	return append(args, prefix+attr.Key, attr.Value.Any())
+}
+
+// logAt 按级别调用 Logger，Logger 不支持的级别按 LeveledLogger 的约定降级或升级
+func logAt(ctx context.Context, l Logger, level slog.Level, msg string, args ...interface{}) {
+	switch {
+	case level < slog.LevelInfo:
+		if d, ok := l.(interface {
+			Debug(ctx context.Context, msg string, args ...interface{})
+		}); ok {
+			d.Debug(ctx, msg, args...)
+			return
+		}
+		l.Info(ctx, msg, args...)
+	case level < slog.LevelWarn:
+		l.Info(ctx, msg, args...)
+	case level < slog.LevelError:
+		if w, ok := l.(interface {
+			Warn(ctx context.Context, msg string, args ...interface{})
+		}); ok {
+			w.Warn(ctx, msg, args...)
+			return
+		}
+		l.Error(ctx, msg, args...)
+	default:
+		l.Error(ctx, msg, args...)
+	}
+}
+
+// defaultLogger 返回输出到 stderr 的默认日志，级别由环境变量 CRAB_LOG_LEVEL 控制，off 时返回 nil
+func defaultLogger() Logger {
+	var level slog.Level
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(LogLevelEnv))) {
+	case "off", "none", "silent":
+		return nil
+	case "debug":
+		level = slog.LevelDebug
+	case "warn", "warning":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	default:
+		level = slog.LevelInfo
+	}
+	return FromSlog(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
+
+func (a *App) logAt(level slog.Level, msg string, args ...interface{}) {
+	if a.logger == nil {
+		return
+	}
+	args = append([]interface{}{"app_id", a.id}, args...)
+	logAt(a.ctx, a.logger, level, msg, args...)
+}
+
+func (a *App) debug(msg string, args ...interface{}) {
+	a.logAt(slog.LevelDebug, msg, args...)
+}
+
+func (a *App) log(msg string, args ...interface{}) {
+	a.logAt(slog.LevelInfo, msg, args...)
+}
+
+func (a *App) warn(msg string, args ...interface{}) {
+	a.logAt(slog.LevelWarn, msg, args...)
+}
+
+func (a *App) err(msg string, args ...interface{}) {
+	a.logAt(slog.LevelError, msg, args...)
+}
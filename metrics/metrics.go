@@ -0,0 +1,303 @@
+// Package metrics 以 Prometheus 文本格式暴露 crab 的生命周期指标，只依赖标准库。
+//
+//	m := metrics.New()
+//	app := crab.New(crab.WithObserver(m.Observe))
+//	mux.Handle("/metrics", m.Handler())
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bang-go/crab"
+)
+
+// DefaultBuckets 钩子耗时直方图的默认分桶（秒），覆盖从毫秒级到分钟级的启动/关闭耗时
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// processStart 近似进程启动时间（本包初始化时间）
+var processStart = time.Now()
+
+// appStates 是 crab_app_state 的全部取值
+var appStates = []string{"new", "starting", "running", "stopping", "stopped"}
+
+// Option 配置 Collector
+type Option func(*Collector)
+
+// WithBuckets 设置钩子耗时直方图的分桶上界（秒）
+func WithBuckets(buckets ...float64) Option {
+	return func(c *Collector) {
+		c.buckets = slices.Sorted(slices.Values(buckets))
+	}
+}
+
+// Collector 从 crab 的生命周期事件中汇总指标，可同时观察多个 App：
+// 每个 App 的指标以 app_id 标签（即 App.GetID()）区分
+type Collector struct {
+	mu      sync.Mutex
+	buckets []float64
+	apps    map[string]*appMetrics // app_id -> 指标
+}
+
+// appMetrics 是单个 App 的指标
+type appMetrics struct {
+	startDuration  map[string]*histogram // hook -> 启动耗时
+	stopDuration   map[string]*histogram // hook -> 关闭耗时
+	startFailures  map[string]uint64     // hook -> 启动失败次数
+	shutdownErrors map[string]uint64     // hook -> 关闭失败次数
+	panics         map[panicKey]uint64
+	rollbacks      uint64
+	state          string
+	timeToReady    time.Duration
+	ready          bool
+}
+
+type panicKey struct {
+	hook  string
+	phase string
+}
+
+// New 创建 Collector
+func New(opts ...Option) *Collector {
+	c := &Collector{
+		buckets: DefaultBuckets,
+		apps:    make(map[string]*appMetrics),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// app 返回 id 对应 App 的指标，第一次观察到该 App 时创建
+func (c *Collector) app(id string) *appMetrics {
+	m := c.apps[id]
+	if m == nil {
+		m = &appMetrics{
+			startDuration:  make(map[string]*histogram),
+			stopDuration:   make(map[string]*histogram),
+			startFailures:  make(map[string]uint64),
+			shutdownErrors: make(map[string]uint64),
+			panics:         make(map[panicKey]uint64),
+			state:          "new",
+		}
+		c.apps[id] = m
+	}
+	return m
+}
+
+// Observe 处理一条生命周期事件，通过 crab.WithObserver(c.Observe) 注册
+func (c *Collector) Observe(e crab.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := c.app(e.AppID)
+
+	switch e.Type {
+	case crab.EventAppStarting:
+		m.state = "starting"
+	case crab.EventAppStarted:
+		m.state = "running"
+		m.timeToReady, m.ready = e.Time.Sub(processStart), true
+	case crab.EventAppStopping:
+		m.state = "stopping"
+	case crab.EventAppStopped:
+		m.state = "stopped"
+	case crab.EventRollback:
+		m.rollbacks++
+		m.state = "stopping"
+	case crab.EventHookStartEnd:
+		c.observe(m.startDuration, e.Hook, e.Duration)
+		if e.Err != nil {
+			m.startFailures[e.Hook]++
+		}
+	case crab.EventHookStopEnd:
+		c.observe(m.stopDuration, e.Hook, e.Duration)
+		if e.Err != nil {
+			m.shutdownErrors[e.Hook]++
+		}
+	case crab.EventShutdownCallbackPanic:
+		m.panics[panicKey{phase: "shutdown_callback"}]++
+	}
+
+	switch e.Type {
+	case crab.EventHookStartEnd, crab.EventHookStopEnd, crab.EventHookRetry,
+		crab.EventHookServeExit, crab.EventHookRestart:
+		if e.Panic != nil {
+			m.panics[panicKey{hook: e.Hook, phase: string(e.Phase)}]++
+		}
+	}
+}
+
+func (c *Collector) observe(hs map[string]*histogram, hook string, d time.Duration) {
+	h := hs[hook]
+	if h == nil {
+		h = newHistogram(c.buckets)
+		hs[hook] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// Handler 返回以 Prometheus 文本格式输出指标的 http.Handler，可挂载到业务自己的 ServeMux 上
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = c.Write(w)
+	})
+}
+
+// Write 以 Prometheus 文本格式写出当前的全部指标，同名指标的各 App 样本写在同一个 HELP/TYPE 之下
+func (c *Collector) Write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := sortedKeys(c.apps)
+	b := bufio.NewWriter(w)
+	writeHistograms(b, "crab_hook_start_duration_seconds", "Duration of hook OnStart, including retries.", ids,
+		func(id string) map[string]*histogram { return c.apps[id].startDuration })
+	writeHistograms(b, "crab_hook_stop_duration_seconds", "Duration of stopping a hook, including waiting for Serve to exit.", ids,
+		func(id string) map[string]*histogram { return c.apps[id].stopDuration })
+
+	writeHeader(b, "crab_app_state", "gauge", "Current lifecycle state of the app (1 for the current state).")
+	for _, id := range ids {
+		for _, s := range appStates {
+			v := 0.0
+			if s == c.apps[id].state {
+				v = 1
+			}
+			writeSample(b, "crab_app_state", labels("app_id", id, "state", s), v)
+		}
+	}
+
+	writeCounters(b, "crab_hook_start_failures_total", "Number of hooks that failed to start.", ids,
+		func(id string) map[string]uint64 { return c.apps[id].startFailures })
+	writeHeader(b, "crab_rollbacks_total", "counter", "Number of startup rollbacks.")
+	for _, id := range ids {
+		writeSample(b, "crab_rollbacks_total", labels("app_id", id), float64(c.apps[id].rollbacks))
+	}
+	writeCounters(b, "crab_shutdown_errors_total", "Number of hooks that failed to stop.", ids,
+		func(id string) map[string]uint64 { return c.apps[id].shutdownErrors })
+
+	writeHeader(b, "crab_panics_recovered_total", "counter", "Number of panics recovered from hooks and shutdown callbacks.")
+	for _, id := range ids {
+		panics := c.apps[id].panics
+		keys := make([]panicKey, 0, len(panics))
+		for k := range panics {
+			keys = append(keys, k)
+		}
+		slices.SortFunc(keys, func(x, y panicKey) int {
+			return strings.Compare(x.hook+"\x00"+x.phase, y.hook+"\x00"+y.phase)
+		})
+		for _, k := range keys {
+			writeSample(b, "crab_panics_recovered_total", labels("app_id", id, "hook", k.hook, "phase", k.phase), float64(panics[k]))
+		}
+	}
+
+	writeHeader(b, "crab_process_start_time_seconds", "gauge", "Start time of the process since unix epoch in seconds.")
+	writeSample(b, "crab_process_start_time_seconds", "", float64(processStart.UnixNano())/1e9)
+	writeHeader(b, "crab_time_to_ready_seconds", "gauge", "Time from process start until all hooks started.")
+	for _, id := range ids {
+		if m := c.apps[id]; m.ready {
+			writeSample(b, "crab_time_to_ready_seconds", labels("app_id", id), m.timeToReady.Seconds())
+		}
+	}
+	return b.Flush()
+}
+
+// histogram 是累积分桶的直方图
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // counts[k] 为 <= buckets[k] 的样本数
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for k, ub := range h.buckets {
+		if v <= ub {
+			h.counts[k]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func writeHistograms(w io.Writer, name, help string, ids []string, get func(id string) map[string]*histogram) {
+	writeHeader(w, name, "histogram", help)
+	for _, id := range ids {
+		hs := get(id)
+		for _, hook := range sortedKeys(hs) {
+			h := hs[hook]
+			for k, ub := range h.buckets {
+				writeSample(w, name+"_bucket", labels("app_id", id, "hook", hook, "le", formatFloat(ub)), float64(h.counts[k]))
+			}
+			writeSample(w, name+"_bucket", labels("app_id", id, "hook", hook, "le", "+Inf"), float64(h.count))
+			writeSample(w, name+"_sum", labels("app_id", id, "hook", hook), h.sum)
+			writeSample(w, name+"_count", labels("app_id", id, "hook", hook), float64(h.count))
+		}
+	}
+}
+
+func writeCounters(w io.Writer, name, help string, ids []string, get func(id string) map[string]uint64) {
+	writeHeader(w, name, "counter", help)
+	for _, id := range ids {
+		counters := get(id)
+		for _, hook := range sortedKeys(counters) {
+			writeSample(w, name, labels("app_id", id, "hook", hook), float64(counters[hook]))
+		}
+	}
+}
+
+func writeHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeSample(w io.Writer, name, labels string, v float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(v))
+}
+
+// labels 将成对的标签名和值格式化为 {k="v",...}
+func labels(kv ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for k := 0; k+1 < len(kv); k += 2 {
+		if k > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(kv[k])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(kv[k+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bang-go/crab"
+)
+
+func TestWrite(t *testing.T) {
+	start := processStart
+	processStart = time.Unix(1700000000, 0)
+	defer func() { processStart = start }()
+
+	errBoom := errors.New("boom")
+	c := New(WithBuckets(1, 0.1))
+	for _, e := range []crab.Event{
+		{AppID: "api", Type: crab.EventAppStarting},
+		{AppID: "api", Type: crab.EventHookStartEnd, Hook: "db", Duration: 50 * time.Millisecond},
+		{AppID: "api", Type: crab.EventHookStartEnd, Hook: "cache \"a\\b\"\n", Duration: 2 * time.Second, Err: errBoom},
+		{AppID: "api", Type: crab.EventRollback, Hook: "cache \"a\\b\"\n", Err: errBoom},
+		{AppID: "api", Type: crab.EventHookStopEnd, Hook: "db", Duration: 500 * time.Millisecond, Err: errBoom},
+		{AppID: "api", Type: crab.EventAppStopped},
+
+		{AppID: "worker", Type: crab.EventAppStarting},
+		{AppID: "worker", Type: crab.EventHookStartEnd, Hook: "db", Duration: 500 * time.Millisecond},
+		{AppID: "worker", Type: crab.EventHookStartEnd, Hook: "db", Duration: 100 * time.Millisecond},
+		{AppID: "worker", Type: crab.EventAppStarted, Time: processStart.Add(1500 * time.Millisecond)},
+		{AppID: "worker", Type: crab.EventHookServeExit, Hook: "consumer", Phase: crab.PhaseServe, Panic: "kaboom"},
+		{AppID: "worker", Type: crab.EventShutdownCallbackPanic, Panic: "kaboom"},
+		{AppID: "worker", Type: crab.EventAppStopping},
+	} {
+		c.Observe(e)
+	}
+
+	var b strings.Builder
+	if err := c.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != golden {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, golden)
+	}
+}
+
+// golden 是 TestWrite 的期望输出：分桶累积计数、+Inf 桶等于样本数、标签值转义，每个指标族只有一组 HELP/TYPE
+const golden = `# HELP crab_hook_start_duration_seconds Duration of hook OnStart, including retries.
+# TYPE crab_hook_start_duration_seconds histogram
+crab_hook_start_duration_seconds_bucket{app_id="api",hook="cache \"a\\b\"\n",le="0.1"} 0
+crab_hook_start_duration_seconds_bucket{app_id="api",hook="cache \"a\\b\"\n",le="1"} 0
+crab_hook_start_duration_seconds_bucket{app_id="api",hook="cache \"a\\b\"\n",le="+Inf"} 1
+crab_hook_start_duration_seconds_sum{app_id="api",hook="cache \"a\\b\"\n"} 2
+crab_hook_start_duration_seconds_count{app_id="api",hook="cache \"a\\b\"\n"} 1
+crab_hook_start_duration_seconds_bucket{app_id="api",hook="db",le="0.1"} 1
+crab_hook_start_duration_seconds_bucket{app_id="api",hook="db",le="1"} 1
+crab_hook_start_duration_seconds_bucket{app_id="api",hook="db",le="+Inf"} 1
+crab_hook_start_duration_seconds_sum{app_id="api",hook="db"} 0.05
+crab_hook_start_duration_seconds_count{app_id="api",hook="db"} 1
+crab_hook_start_duration_seconds_bucket{app_id="worker",hook="db",le="0.1"} 1
+crab_hook_start_duration_seconds_bucket{app_id="worker",hook="db",le="1"} 2
+crab_hook_start_duration_seconds_bucket{app_id="worker",hook="db",le="+Inf"} 2
+crab_hook_start_duration_seconds_sum{app_id="worker",hook="db"} 0.6
+crab_hook_start_duration_seconds_count{app_id="worker",hook="db"} 2
+# HELP crab_hook_stop_duration_seconds Duration of stopping a hook, including waiting for Serve to exit.
+# TYPE crab_hook_stop_duration_seconds histogram
+crab_hook_stop_duration_seconds_bucket{app_id="api",hook="db",le="0.1"} 0
+crab_hook_stop_duration_seconds_bucket{app_id="api",hook="db",le="1"} 1
+crab_hook_stop_duration_seconds_bucket{app_id="api",hook="db",le="+Inf"} 1
+crab_hook_stop_duration_seconds_sum{app_id="api",hook="db"} 0.5
+crab_hook_stop_duration_seconds_count{app_id="api",hook="db"} 1
+# HELP crab_app_state Current lifecycle state of the app (1 for the current state).
+# TYPE crab_app_state gauge
+crab_app_state{app_id="api",state="new"} 0
+crab_app_state{app_id="api",state="starting"} 0
+crab_app_state{app_id="api",state="running"} 0
+crab_app_state{app_id="api",state="stopping"} 0
+crab_app_state{app_id="api",state="stopped"} 1
+crab_app_state{app_id="worker",state="new"} 0
+crab_app_state{app_id="worker",state="starting"} 0
+crab_app_state{app_id="worker",state="running"} 0
+crab_app_state{app_id="worker",state="stopping"} 1
+crab_app_state{app_id="worker",state="stopped"} 0
+# HELP crab_hook_start_failures_total Number of hooks that failed to start.
+# TYPE crab_hook_start_failures_total counter
+crab_hook_start_failures_total{app_id="api",hook="cache \"a\\b\"\n"} 1
+# HELP crab_rollbacks_total Number of startup rollbacks.
+# TYPE crab_rollbacks_total counter
+crab_rollbacks_total{app_id="api"} 1
+crab_rollbacks_total{app_id="worker"} 0
+# HELP crab_shutdown_errors_total Number of hooks that failed to stop.
+# TYPE crab_shutdown_errors_total counter
+crab_shutdown_errors_total{app_id="api",hook="db"} 1
+# HELP crab_panics_recovered_total Number of panics recovered from hooks and shutdown callbacks.
+# TYPE crab_panics_recovered_total counter
+crab_panics_recovered_total{app_id="worker",hook="",phase="shutdown_callback"} 1
+crab_panics_recovered_total{app_id="worker",hook="consumer",phase="serve"} 1
+# HELP crab_process_start_time_seconds Start time of the process since unix epoch in seconds.
+# TYPE crab_process_start_time_seconds gauge
+crab_process_start_time_seconds 1.7e+09
+# HELP crab_time_to_ready_seconds Time from process start until all hooks started.
+# TYPE crab_time_to_ready_seconds gauge
+crab_time_to_ready_seconds{app_id="worker"} 1.5
+`
@@ -0,0 +1,231 @@
+package crab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Module 将一组钩子打包为一个模块，App 将其视为一个整体：
+//
+//   - 模块内钩子的名称带有 "name/" 前缀（嵌套模块为 "a/b/..."），出现在日志、事件与 Run 返回的错误中；
+//   - 模块内钩子的 DependsOn 优先解析为同一模块内的钩子，模块外只能通过带前缀的全名引用模块内部的钩子；
+//   - 其他钩子可以依赖模块名，相当于依赖模块内的所有钩子，因此模块会作为一个单元先于它们启动、晚于它们关闭；
+//   - 返回的 Hook 上设置的 DependsOn 作用于模块内所有钩子，StartTimeout / StopTimeout 是整个模块启动 / 关闭的总时限，
+//     Critical 标记模块内所有钩子为关键钩子。模块本身不能设置 OnStart、OnStop 等回调
+//
+// 例如存储团队可以提供一个可复用的模块：
+//
+//	storage := crab.Module("storage", mysqlHook, redisHook)
+//	storage.StartTimeout = 10 * time.Second
+//	app.Add(storage, crab.Hook{Name: "api", DependsOn: []string{"storage"}, ...})
+func Module(name string, hooks ...Hook) Hook {
+	return Hook{Name: name, Source: callerSource(1), module: append([]Hook{}, hooks...)}
+}
+
+// moduleRun 记录模块在一次启动 / 关闭中的共享时限，第一个钩子开始启动 / 关闭时计时
+type moduleRun struct {
+	name         string
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+
+	startOnce     sync.Once
+	startDeadline time.Time
+	stopOnce      sync.Once
+	stopDeadline  time.Time
+}
+
+// deadline 返回模块在 phase 阶段的截止时间，没有设置时限时返回零值
+func (m *moduleRun) deadline(phase Phase) time.Time {
+	switch phase {
+	case PhaseStart:
+		if m.startTimeout > 0 {
+			m.startOnce.Do(func() { m.startDeadline = time.Now().Add(m.startTimeout) })
+		}
+		return m.startDeadline
+	case PhaseStop:
+		if m.stopTimeout > 0 {
+			m.stopOnce.Do(func() { m.stopDeadline = time.Now().Add(m.stopTimeout) })
+		}
+		return m.stopDeadline
+	}
+	return time.Time{}
+}
+
+func (m *moduleRun) timeout(phase Phase) time.Duration {
+	if phase == PhaseStart {
+		return m.startTimeout
+	}
+	return m.stopTimeout
+}
+
+// moduleContext 为钩子 i 派生受其所在模块（由外到内）时限约束的 Context，
+// check 在钩子失败后将模块超时导致的错误标记为 ErrHookTimeout
+func (a *App) moduleContext(ctx context.Context, i int, phase Phase) (_ context.Context, check func(error) error, cancel context.CancelFunc) {
+	check = func(err error) error { return err }
+	cancel = func() {}
+	if i >= len(a.modules) {
+		return ctx, check, cancel
+	}
+
+	parent := ctx
+	var cancels []context.CancelFunc
+	for _, m := range a.modules[i] {
+		deadline := m.deadline(phase)
+		if deadline.IsZero() {
+			continue
+		}
+		var c context.CancelFunc
+		ctx, c = context.WithDeadlineCause(ctx, deadline, &moduleTimeout{module: m.name, phase: phase, timeout: m.timeout(phase)})
+		cancels = append(cancels, c)
+	}
+	if len(cancels) == 0 {
+		return ctx, check, cancel
+	}
+
+	check = func(err error) error {
+		var mt *moduleTimeout
+		if err != nil && parent.Err() == nil && errors.As(context.Cause(ctx), &mt) && !errors.Is(err, ErrHookTimeout) {
+			return fmt.Errorf("%w: %w", mt, err)
+		}
+		return err
+	}
+	cancel = func() {
+		for _, c := range slices.Backward(cancels) {
+			c()
+		}
+	}
+	return ctx, check, cancel
+}
+
+// moduleTimeout 是模块时限到期的原因
+type moduleTimeout struct {
+	module  string
+	phase   Phase
+	timeout time.Duration
+}
+
+func (e *moduleTimeout) Error() string {
+	return fmt.Sprintf("%v: module [%s] %s exceeded %v", ErrHookTimeout, e.module, e.phase, e.timeout)
+}
+
+func (e *moduleTimeout) Unwrap() error { return ErrHookTimeout }
+
+// moduleLeaf 是展开后的钩子及其所在的模块链（由外到内）
+type moduleLeaf struct {
+	hook   Hook
+	scope  []string // 所在模块的全名，由外到内
+	chain  []*moduleRun
+	extern [][]string // 各层模块声明的 DependsOn，与 scope 一一对应
+}
+
+// flattenModules 将模块展开为带前缀名称的普通钩子，并按模块作用域将 DependsOn 解析为全名。
+// 返回展开后的钩子及每个钩子所在的模块链；没有模块时原样返回
+func flattenModules(hooks []Hook) ([]Hook, [][]*moduleRun, error) {
+	if !slices.ContainsFunc(hooks, func(h Hook) bool { return h.module != nil }) {
+		return hooks, nil, nil
+	}
+
+	var leaves []moduleLeaf
+	members := make(map[string][]string) // 模块全名 -> 模块内所有钩子的全名
+	names := make(map[string]bool)       // 所有钩子的全名
+
+	var walk func(hooks []Hook, prefix string, scope []string, chain []*moduleRun, extern [][]string, critical bool, provider *provider) error
+	walk = func(hooks []Hook, prefix string, scope []string, chain []*moduleRun, extern [][]string, critical bool, provider *provider) error {
+		for k, h := range hooks {
+			name := h.Name
+			if prefix != "" {
+				name = prefix + hookName(h, k)
+			}
+			if h.provider == nil {
+				h.provider = provider
+			}
+			if h.module == nil {
+				h.Name = name
+				h.Critical = h.Critical || critical
+				if name != "" {
+					names[name] = true
+				}
+				leaves = append(leaves, moduleLeaf{hook: h, scope: scope, chain: chain, extern: extern})
+				continue
+			}
+
+			if h.Name == "" {
+				return errors.New("crab: module must have a name")
+			}
+			if h.OnStart != nil || h.OnStop != nil || h.Serve != nil || h.Health != nil || h.OnReload != nil || h.OnReloadRollback != nil {
+				return fmt.Errorf("crab: module [%s] cannot define its own callbacks", name)
+			}
+			if _, dup := members[name]; dup {
+				return fmt.Errorf("%w: module [%s] is registered more than once", ErrInvalidDependency, name)
+			}
+			members[name] = nil
+			m := &moduleRun{name: name, startTimeout: h.StartTimeout, stopTimeout: h.StopTimeout}
+			first := len(leaves)
+			err := walk(h.module, name+"/",
+				append(slices.Clip(scope), name),
+				append(slices.Clip(chain), m),
+				append(slices.Clip(extern), h.DependsOn),
+				critical || h.Critical,
+				h.provider)
+			if err != nil {
+				return err
+			}
+			for _, l := range leaves[first:] {
+				members[name] = append(members[name], l.hook.Name)
+			}
+		}
+		return nil
+	}
+	if err := walk(hooks, "", nil, nil, nil, false, nil); err != nil {
+		return nil, nil, err
+	}
+
+	// resolve 在 scope 指定的模块作用域内由内向外查找依赖，找不到时原样返回，由 resolveOrder 报告
+	resolve := func(scope []string, dep string) []string {
+		for k := len(scope); k >= 0; k-- {
+			full := dep
+			if k > 0 {
+				full = scope[k-1] + "/" + dep
+			}
+			if ms, ok := members[full]; ok {
+				return ms
+			}
+			if names[full] {
+				return []string{full}
+			}
+		}
+		return []string{dep}
+	}
+
+	flat := make([]Hook, len(leaves))
+	chains := make([][]*moduleRun, len(leaves))
+	for i, l := range leaves {
+		h := l.hook
+		var deps []string
+		seen := make(map[string]bool)
+		add := func(scope []string, dep string) {
+			for _, d := range resolve(scope, dep) {
+				if !seen[d] {
+					seen[d] = true
+					deps = append(deps, d)
+				}
+			}
+		}
+		for _, dep := range h.DependsOn {
+			add(l.scope, dep)
+		}
+		// 模块声明的依赖在模块所在的作用域中解析
+		for k, ds := range l.extern {
+			for _, dep := range ds {
+				add(l.scope[:k], dep)
+			}
+		}
+		h.DependsOn = deps
+		flat[i], chains[i] = h, l.chain
+	}
+	return flat, chains, nil
+}
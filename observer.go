@@ -0,0 +1,121 @@
+package crab
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// EventType 生命周期事件类型
+type EventType string
+
+const (
+	EventAppStarting  EventType = "app_starting"  // Run 开始
+	EventAppStarted   EventType = "app_started"   // 所有钩子启动完成，Duration 为启动总耗时
+	EventAppDraining  EventType = "app_draining"  // 收到关闭信号，标记为未就绪并等待流量排空，Duration 为排空等待时间
+	EventAppStopping  EventType = "app_stopping"  // Stop 开始
+	EventAppStopped   EventType = "app_stopped"   // 关闭流程结束，Duration 为关闭总耗时，Err 为关闭结果
+	EventAppReloading EventType = "app_reloading" // Reload 开始
+	EventAppReloaded  EventType = "app_reloaded"  // Reload 结束，Err 非 nil 表示重载失败并已回滚
+
+	EventHookStartBegin EventType = "hook_start_begin" // 开始调用 OnStart
+	EventHookStartEnd   EventType = "hook_start_end"   // OnStart 结束（包括重试），Err 非 nil 表示启动失败
+	EventHookStopBegin  EventType = "hook_stop_begin"  // 开始关闭钩子（取消 Serve 并调用 OnStop）
+	EventHookStopEnd    EventType = "hook_stop_end"    // 钩子关闭结束，Err 非 nil 表示关闭失败
+	EventHookRetry      EventType = "hook_retry"       // OnStart 失败后即将重试，Attempt 为失败的尝试序号
+	EventHookServeExit  EventType = "hook_serve_exit"  // Serve 在关闭流程之外返回
+	EventHookRestart    EventType = "hook_restart"     // 监督者重启组件结束，Err 非 nil 表示重启失败
+
+	EventRollback              EventType = "rollback"                // 启动失败，开始回滚，Err 为启动失败的原因
+	EventSignal                EventType = "signal_received"         // 收到系统信号，关闭信号的 Attempt 为第几次收到
+	EventShutdownCallbackPanic EventType = "shutdown_callback_panic" // OnShutdown 回调 panic
+	EventForcedShutdown        EventType = "forced_shutdown"         // 关闭期间再次收到信号，只关闭关键钩子
+	EventForcedExit            EventType = "forced_exit"             // 多次收到信号，立即退出进程
+)
+
+// Event 是一条结构化的生命周期事件，未涉及的字段为零值
+type Event struct {
+	Type     EventType
+	AppID    string
+	Time     time.Time
+	Hook     string        // 钩子名称，仅钩子事件
+	Phase    Phase         // 钩子所处阶段，仅钩子事件
+	Attempt  int           // 重试或重启的尝试序号
+	Duration time.Duration // 对应阶段的耗时，仅 *End / AppStarted / AppStopped 等结束事件
+	Err      error
+	Panic    any // 钩子或回调 panic 时恢复的值
+	Signal   os.Signal
+}
+
+type observer struct {
+	id int
+	fn func(Event)
+}
+
+// WithObserver 注册生命周期事件观察者，效果等同于在 Run 之前调用 Subscribe
+func WithObserver(fn func(Event)) Option {
+	return func(a *App) {
+		a.Subscribe(fn)
+	}
+}
+
+// Subscribe 订阅生命周期事件，返回取消订阅的函数。
+// 事件在产生它的 goroutine 中同步分发，观察者应尽快返回；
+// 并行生命周期下观察者可能被并发调用，需要自行保证并发安全
+func (a *App) Subscribe(fn func(Event)) (unsubscribe func()) {
+	a.obsMu.Lock()
+	defer a.obsMu.Unlock()
+	a.obsSeq++
+	id := a.obsSeq
+	a.observers = append(a.observers, observer{id: id, fn: fn})
+
+	return func() {
+		a.obsMu.Lock()
+		defer a.obsMu.Unlock()
+		for k, o := range a.observers {
+			if o.id == id {
+				a.observers = append(a.observers[:k:k], a.observers[k+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit 按订阅顺序将事件分发给所有观察者，观察者的 panic 会被恢复并记录
+func (a *App) emit(e Event) {
+	a.obsMu.RLock()
+	observers := a.observers
+	a.obsMu.RUnlock()
+	if len(observers) == 0 {
+		return
+	}
+
+	e.AppID = a.id
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for _, o := range observers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					a.err("Observer panicked", "event", e.Type, "panic", r)
+				}
+			}()
+			o.fn(e)
+		}()
+	}
+}
+
+// emitHook 分发钩子 i 的事件，并从 err 中提取 panic 信息
+func (a *App) emitHook(typ EventType, i int, phase Phase, d time.Duration, err error) {
+	a.emit(Event{Type: typ, Hook: hookName(a.hooks[i], i), Phase: phase, Duration: d, Err: err, Panic: panicValue(err)})
+}
+
+// panicValue 返回 err 中 *PanicError 恢复的值，err 不是 panic 时返回 nil
+func panicValue(err error) any {
+	var pe *PanicError
+	if err != nil && errors.As(err, &pe) {
+		return pe.Value
+	}
+	return nil
+}
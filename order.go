@@ -0,0 +1,208 @@
+package crab
+
+import (
+	"cmp"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// hookName 返回钩子在日志中的名称，未命名的钩子使用注册位置，没有注册位置时使用注册序号
+func hookName(h Hook, i int) string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.Source != "":
+		return h.Source
+	}
+	return fmt.Sprintf("hook#%d", i)
+}
+
+// describeHook 返回用于错误信息的钩子描述，命名的钩子附带注册位置
+func describeHook(h Hook, i int) string {
+	if h.Name != "" && h.Source != "" {
+		return fmt.Sprintf("[%s] (registered at %s)", h.Name, h.Source)
+	}
+	return "[" + hookName(h, i) + "]"
+}
+
+// withSource 在钩子没有注册位置时记录调用栈上第 skip 层调用者（0 为 withSource 的调用者）
+func withSource(h Hook, skip int) Hook {
+	if h.Source == "" {
+		h.Source = callerSource(skip + 1)
+	}
+	return h
+}
+
+// callerSource 返回调用栈上第 skip 层调用者的位置，格式为 "pkg.Func (dir/file.go:42)"
+func callerSource(skip int) string {
+	pc, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	// 只保留最后一级目录，足以定位又不会过长
+	if k := strings.LastIndex(file, "/"); k >= 0 {
+		if k2 := strings.LastIndex(file[:k], "/"); k2 >= 0 {
+			file = file[k2+1:]
+		}
+	}
+	if f := runtime.FuncForPC(pc); f != nil {
+		name := f.Name()
+		if k := strings.LastIndex(name, "/"); k >= 0 {
+			name = name[k+1:]
+		}
+		return fmt.Sprintf("%s (%s:%d)", name, file, line)
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// resolveOrder 根据 DependsOn 计算钩子的启动顺序（拓扑排序）
+// 返回 hooks 的下标序列及每个钩子直接依赖的下标；没有依赖约束的钩子之间保持 Add 顺序，
+// 引用不存在的名称、引用重名钩子或存在循环依赖时返回错误
+func resolveOrder(hooks []Hook) ([]int, [][]int, error) {
+	byName := make(map[string][]int, len(hooks))
+	for i, h := range hooks {
+		if h.Name != "" {
+			byName[h.Name] = append(byName[h.Name], i)
+		}
+	}
+
+	deps := make([][]int, len(hooks))
+	for i, h := range hooks {
+		for _, dep := range h.DependsOn {
+			targets := byName[dep]
+			switch {
+			case len(targets) == 0:
+				return nil, nil, fmt.Errorf("%w: hook %s depends on unknown hook [%s]", ErrInvalidDependency, describeHook(h, i), dep)
+			case len(targets) > 1:
+				return nil, nil, fmt.Errorf("%w: hook %s depends on [%s], which is registered %d times at %s", ErrInvalidDependency, describeHook(h, i), dep, len(targets), describeSources(hooks, targets))
+			}
+			deps[i] = append(deps[i], targets[0])
+		}
+	}
+
+	// 通过注入的 Lifecycle 注册的钩子排在其依赖的构造函数注册的钩子之后
+	byProvider := make(map[*provider][]int)
+	for i, h := range hooks {
+		if h.provider != nil {
+			byProvider[h.provider] = append(byProvider[h.provider], i)
+		}
+	}
+	for i, h := range hooks {
+		if h.provider == nil {
+			continue
+		}
+		for _, p := range h.provider.ancestors() {
+			deps[i] = append(deps[i], byProvider[p]...)
+		}
+	}
+
+	// Kahn 算法：每次选取下标最小的就绪钩子，保证结果稳定
+	pending := make([]int, len(hooks))
+	dependents := make([][]int, len(hooks))
+	for i, ds := range deps {
+		pending[i] = len(ds)
+		for _, d := range ds {
+			dependents[d] = append(dependents[d], i)
+		}
+	}
+
+	order := make([]int, 0, len(hooks))
+	done := make([]bool, len(hooks))
+	for len(order) < len(hooks) {
+		next := -1
+		for i := range hooks {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, nil, fmt.Errorf("%w: dependency cycle %s", ErrInvalidDependency, describeCycle(hooks, deps, done))
+		}
+		done[next] = true
+		order = append(order, next)
+		for _, d := range dependents[next] {
+			pending[d]--
+		}
+	}
+	return order, deps, nil
+}
+
+// describeSources 列出重名钩子的注册位置
+func describeSources(hooks []Hook, targets []int) string {
+	sources := make([]string, len(targets))
+	for k, i := range targets {
+		sources[k] = cmp.Or(hooks[i].Source, fmt.Sprintf("hook#%d", i))
+	}
+	return strings.Join(sources, ", ")
+}
+
+// groupTiers 将拓扑序划分为层级：每个钩子所在层级比其依赖的最高层级大 1，
+// 同一层级内的钩子之间没有依赖约束，可以并发启动
+func groupTiers(order []int, deps [][]int) [][]int {
+	level := make([]int, len(deps))
+	var tiers [][]int
+	for _, i := range order {
+		l := 0
+		for _, d := range deps[i] {
+			l = max(l, level[d]+1)
+		}
+		level[i] = l
+		if l == len(tiers) {
+			tiers = append(tiers, nil)
+		}
+		tiers[l] = append(tiers[l], i)
+	}
+	return tiers
+}
+
+// describeCycle 在尚未排序的钩子中找出一个环，格式化为 "a -> b -> a"
+func describeCycle(hooks []Hook, deps [][]int, done []bool) string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make([]int, len(hooks))
+	var stack []int
+	var cycle []int
+
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		marks[i] = visiting
+		stack = append(stack, i)
+		for _, d := range deps[i] {
+			if done[d] {
+				continue
+			}
+			if marks[d] == visiting {
+				for k, s := range stack {
+					if s == d {
+						cycle = append(append([]int{}, stack[k:]...), d)
+						break
+					}
+				}
+				return true
+			}
+			if marks[d] == unvisited && visit(d) {
+				return true
+			}
+		}
+		stack = stack[:len(stack)-1]
+		marks[i] = visited
+		return false
+	}
+
+	for i := range hooks {
+		if !done[i] && marks[i] == unvisited && visit(i) {
+			break
+		}
+	}
+
+	names := make([]string, len(cycle))
+	for k, i := range cycle {
+		names[k] = hookName(hooks[i], i)
+	}
+	return strings.Join(names, " -> ")
+}
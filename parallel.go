@@ -0,0 +1,99 @@
+package crab
+
+import (
+	"context"
+	"sync"
+)
+
+// startParallel 按层级启动钩子：同一层级内并发执行，层级之间串行。
+// 任一钩子失败会取消同层的其他钩子，并在整层结束后返回第一个错误，
+// 已成功启动的钩子会记录在 a.started 中，由回滚流程关闭
+func (a *App) startParallel(ctx context.Context) error {
+	for level, tier := range groupTiers(a.order, a.deps) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(tier) > 1 {
+			a.debug("Starting components in parallel...", "tier", level, "count", len(tier))
+		}
+		if err := a.runTier(ctx, tier, true, a.startHook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopParallel 按与启动相反的层级并发关闭已启动的钩子
+func (a *App) stopParallel(ctx context.Context, startedOrder []int, t *shutdownTracker) {
+	started := make(map[int]bool, len(startedOrder))
+	for _, i := range startedOrder {
+		started[i] = true
+	}
+
+	tiers := groupTiers(a.order, a.deps)
+	for level := len(tiers) - 1; level >= 0; level-- {
+		var tier []int
+		for _, i := range tiers[level] {
+			if started[i] && hasStop(a.hooks[i]) {
+				tier = append(tier, i)
+			}
+		}
+		if len(tier) == 0 {
+			continue
+		}
+		if len(tier) > 1 && ctx.Err() == nil {
+			a.debug("Stopping components in parallel...", "tier", level, "count", len(tier))
+		}
+		_ = a.runTier(ctx, tier, false, func(ctx context.Context, i int) error {
+			a.stopOne(ctx, i, t)
+			return nil
+		})
+	}
+}
+
+// runTier 以 maxConcurrency 为上限并发执行同一层级的钩子。
+// failFast 为 true 时，第一个错误会取消传给其余钩子的 Context，且不再调度尚未开始的钩子
+func (a *App) runTier(ctx context.Context, tier []int, failFast bool, fn func(context.Context, int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := a.maxConcurrency
+	if limit <= 0 || limit > len(tier) {
+		limit = len(tier)
+	}
+	sem := make(chan struct{}, limit)
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, i := range tier {
+		if failFast && ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		if failFast && ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, i); err != nil && failFast {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr == nil && failFast {
+		return ctx.Err()
+	}
+	return firstErr
+}
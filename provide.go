@@ -0,0 +1,258 @@
+package crab
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+var (
+	errorType     = reflect.TypeFor[error]()
+	lifecycleType = reflect.TypeFor[Lifecycle]()
+)
+
+// Provide 注册构造函数，Run 时由内置容器按参数类型解析依赖并按需调用（每个构造函数最多调用一次）。
+// 构造函数形如 func(deps...) (T1, T2, ..., error)，最后一个返回值可以是 error；
+// 参数类型为 crab.Lifecycle 时自动注入，通过它注册的钩子会排在其依赖的构造函数注册的钩子之后启动，
+// 因此启动顺序跟随对象依赖图，而不是 Append 的顺序。
+//
+//	app := crab.New(
+//		crab.Provide(NewConfig, NewDB, NewServer),
+//		crab.Invoke(func(srv *http.Server) {}),
+//	)
+func Provide(ctors ...any) Option {
+	return func(a *App) {
+		c := a.containerOf()
+		for _, ctor := range ctors {
+			c.provide(ctor)
+		}
+	}
+}
+
+// Invoke 注册在 Run 开始时按注册顺序调用的函数，其参数与 Provide 的构造函数一样由容器解析；
+// 只有被 Invoke（直接或间接）依赖的构造函数才会被调用。函数可以返回 error，非 nil 时 Run 失败
+func Invoke(fns ...any) Option {
+	return func(a *App) {
+		c := a.containerOf()
+		for _, fn := range fns {
+			c.invoke(fn)
+		}
+	}
+}
+
+// containerOf 返回应用的容器，第一次使用时创建
+func (a *App) containerOf() *container {
+	if a.container == nil {
+		a.container = &container{providers: make(map[reflect.Type]*provider)}
+	}
+	return a.container
+}
+
+// provider 是一个构造函数或 Invoke 函数
+type provider struct {
+	fn   reflect.Value
+	name string
+
+	state    int             // 见 providerPending 等
+	values   []reflect.Value // 构造函数的返回值（不含 error）
+	deps     []*provider     // 直接依赖的构造函数
+	upstream []*provider     // 传递依赖的构造函数，由 ancestors 计算
+}
+
+const (
+	providerPending = iota
+	providerCalling
+	providerDone
+)
+
+// container 是一个只依赖标准库的依赖注入容器
+type container struct {
+	providers map[reflect.Type]*provider
+	invokes   []*provider
+	errs      []error // 注册阶段的错误，Run 时返回
+}
+
+func newProvider(fn any) (*provider, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("%w: %T is not a function", ErrInvalidProvider, fn)
+	}
+	name := funcName(v)
+	t := v.Type()
+	if t.IsVariadic() {
+		return nil, fmt.Errorf("%w: %s must not be variadic", ErrInvalidProvider, name)
+	}
+	for k := range t.NumOut() {
+		if t.Out(k) == errorType && k != t.NumOut()-1 {
+			return nil, fmt.Errorf("%w: %s may only return error as its last result", ErrInvalidProvider, name)
+		}
+	}
+	return &provider{fn: v, name: name}, nil
+}
+
+func (c *container) provide(ctor any) {
+	p, err := newProvider(ctor)
+	if err != nil {
+		c.errs = append(c.errs, err)
+		return
+	}
+	outs := p.outTypes()
+	if len(outs) == 0 {
+		c.errs = append(c.errs, fmt.Errorf("%w: constructor %s must return a value", ErrInvalidProvider, p.name))
+		return
+	}
+	for _, t := range outs {
+		switch prev, dup := c.providers[t]; {
+		case t == lifecycleType:
+			c.errs = append(c.errs, fmt.Errorf("%w: constructor %s must not provide crab.Lifecycle", ErrInvalidProvider, p.name))
+		case dup:
+			c.errs = append(c.errs, fmt.Errorf("%w: %v is provided by both %s and %s", ErrInvalidProvider, t, prev.name, p.name))
+		default:
+			c.providers[t] = p
+		}
+	}
+}
+
+func (c *container) invoke(fn any) {
+	p, err := newProvider(fn)
+	if err != nil {
+		c.errs = append(c.errs, err)
+		return
+	}
+	c.invokes = append(c.invokes, p)
+}
+
+// outTypes 返回构造函数提供的类型（不含 error）
+func (p *provider) outTypes() []reflect.Type {
+	t := p.fn.Type()
+	outs := make([]reflect.Type, 0, t.NumOut())
+	for k := range t.NumOut() {
+		if t.Out(k) != errorType {
+			outs = append(outs, t.Out(k))
+		}
+	}
+	return outs
+}
+
+// run 按注册顺序调用所有 Invoke 函数，构造函数通过 lc 注册的钩子追加到应用中
+func (c *container) run(a *App) error {
+	if err := errors.Join(c.errs...); err != nil {
+		return err
+	}
+	for _, p := range c.invokes {
+		if err := c.call(a, p, []string{p.name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve 返回类型 t 的值，必要时调用其构造函数；path 为当前的解析路径，用于错误信息
+func (c *container) resolve(a *App, t reflect.Type, path []string) (reflect.Value, *provider, error) {
+	p, ok := c.providers[t]
+	if !ok {
+		return reflect.Value{}, nil, fmt.Errorf("%w: missing provider for %v (%s)", ErrInvalidProvider, t, strings.Join(append(path, t.String()), " -> "))
+	}
+	switch p.state {
+	case providerCalling:
+		return reflect.Value{}, nil, fmt.Errorf("%w: dependency cycle %s", ErrInvalidProvider, strings.Join(append(path, t.String()), " -> "))
+	case providerPending:
+		if err := c.call(a, p, append(path, t.String())); err != nil {
+			return reflect.Value{}, nil, err
+		}
+	}
+	for k, out := range p.outTypes() {
+		if out == t {
+			return p.values[k], p, nil
+		}
+	}
+	panic("unreachable")
+}
+
+// call 解析 p 的参数并调用它，构造函数的 panic 与 error 都会作为 Run 的错误返回
+func (c *container) call(a *App, p *provider, path []string) (err error) {
+	p.state = providerCalling
+	t := p.fn.Type()
+	args := make([]reflect.Value, t.NumIn())
+	for k := range t.NumIn() {
+		in := t.In(k)
+		if in == lifecycleType {
+			args[k] = reflect.ValueOf(Lifecycle(&providerLifecycle{app: a, provider: p}))
+			continue
+		}
+		v, dep, err := c.resolve(a, in, path)
+		if err != nil {
+			return err
+		}
+		args[k] = v
+		p.deps = append(p.deps, dep)
+	}
+
+	a.debug("Calling constructor", "constructor", p.name)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("crab: %s: %w", p.name, &PanicError{Value: r, Stack: debug.Stack()})
+		}
+	}()
+	results := p.fn.Call(args)
+	if n := len(results); n > 0 && t.Out(n-1) == errorType {
+		if err, _ := results[n-1].Interface().(error); err != nil {
+			return fmt.Errorf("crab: %s: %w", p.name, err)
+		}
+		results = results[:n-1]
+	}
+	p.values = results
+	p.state = providerDone
+	return nil
+}
+
+// ancestors 返回 p 传递依赖的所有构造函数
+func (p *provider) ancestors() []*provider {
+	if p.upstream != nil || len(p.deps) == 0 {
+		return p.upstream
+	}
+	seen := make(map[*provider]bool)
+	var walk func(q *provider)
+	walk = func(q *provider) {
+		for _, d := range q.deps {
+			if !seen[d] {
+				seen[d] = true
+				p.upstream = append(p.upstream, d)
+				walk(d)
+			}
+		}
+	}
+	walk(p)
+	return p.upstream
+}
+
+// providerLifecycle 是注入给构造函数的 Lifecycle，记录钩子由哪个构造函数注册
+type providerLifecycle struct {
+	app      *App
+	provider *provider
+}
+
+func (l *providerLifecycle) Append(h Hook) {
+	h = withSource(h, 1)
+	if h.provider == nil {
+		h.provider = l.provider
+	}
+	l.app.mu.Lock()
+	defer l.app.mu.Unlock()
+	l.app.hooks = append(l.app.hooks, h)
+}
+
+// funcName 返回函数的名称，如 "main.NewServer"
+func funcName(fn reflect.Value) string {
+	if f := runtime.FuncForPC(fn.Pointer()); f != nil {
+		name := f.Name()
+		if k := strings.LastIndex(name, "/"); k >= 0 {
+			name = name[k+1:]
+		}
+		return name
+	}
+	return fn.Type().String()
+}
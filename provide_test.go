@@ -0,0 +1,110 @@
+package crab_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/bang-go/crab"
+	"github.com/bang-go/crab/crabtest"
+)
+
+type (
+	testConfig struct{}
+	testDB     struct{}
+	testServer struct{}
+)
+
+// startsIn 返回在 OnStart 中等待 d 的钩子
+func startsIn(name string, d time.Duration) crab.Hook {
+	h := crabtest.Hook(name)
+	h.OnStart = func(context.Context) error {
+		time.Sleep(d)
+		return nil
+	}
+	return h
+}
+
+func newTestConfig(lc crab.Lifecycle) *testConfig {
+	lc.Append(startsIn("config", 3*time.Second))
+	return &testConfig{}
+}
+
+func newTestDB(lc crab.Lifecycle, _ *testConfig) (*testDB, error) {
+	lc.Append(startsIn("db", 2*time.Second))
+	return &testDB{}, nil
+}
+
+func newTestServer(lc crab.Lifecycle, _ *testDB) *testServer {
+	lc.Append(startsIn("server", time.Second))
+	return &testServer{}
+}
+
+func newOtherConfig() *testConfig { return &testConfig{} }
+
+type (
+	cycleA struct{}
+	cycleB struct{}
+)
+
+func newCycleA(*cycleB) *cycleA { return &cycleA{} }
+func newCycleB(*cycleA) *cycleB { return &cycleB{} }
+func useCycleA(*cycleA)         {}
+func useServer(*testServer)     {}
+
+func TestProvideHookOrder(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		// 按与依赖相反的顺序 Provide，并行启动：钩子之间没有依赖时 server 会最先完成
+		app, _, rec := newApp(
+			crab.WithParallelLifecycle(0),
+			crab.Provide(newTestServer, newTestDB, newTestConfig),
+			crab.Invoke(useServer),
+		)
+		if err := crabtest.Start(t, app).Stop(); err != nil {
+			t.Fatalf("Stop() = %v", err)
+		}
+		rec.AssertStarted(t, "config", "db", "server")
+		rec.AssertStopped(t, "server", "db", "config")
+	})
+}
+
+func TestProvideErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []crab.Option
+		want string
+	}{
+		{
+			name: "missing provider",
+			opts: []crab.Option{crab.Provide(newTestDB), crab.Invoke(useServer)},
+			want: "invalid provider: missing provider for *crab_test.testServer (crab_test.useServer -> *crab_test.testServer)",
+		},
+		{
+			name: "missing dependency",
+			opts: []crab.Option{crab.Provide(newTestServer, newTestDB), crab.Invoke(useServer)},
+			want: "invalid provider: missing provider for *crab_test.testConfig (crab_test.useServer -> *crab_test.testServer -> *crab_test.testDB -> *crab_test.testConfig)",
+		},
+		{
+			name: "cycle",
+			opts: []crab.Option{crab.Provide(newCycleA, newCycleB), crab.Invoke(useCycleA)},
+			want: "invalid provider: dependency cycle crab_test.useCycleA -> *crab_test.cycleA -> *crab_test.cycleB -> *crab_test.cycleA",
+		},
+		{
+			name: "duplicate type",
+			opts: []crab.Option{crab.Provide(newTestConfig, newOtherConfig)},
+			want: "invalid provider: *crab_test.testConfig is provided by both crab_test.newTestConfig and crab_test.newOtherConfig",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _, rec := newApp(tt.opts...)
+			err := app.Run()
+			if !errors.Is(err, crab.ErrInvalidProvider) || err.Error() != tt.want {
+				t.Fatalf("Run() = %v,\nwant %s", err, tt.want)
+			}
+			rec.AssertStarted(t)
+		})
+	}
+}
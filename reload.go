@@ -0,0 +1,133 @@
+package crab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bang-go/crab/pkg/types"
+)
+
+// WithReloadSignals 设置触发 App.Reload 的系统信号，默认 SIGHUP；不传参数表示不监听。
+// 不要与 WithSignals 的关闭信号重叠
+func WithReloadSignals(signals ...os.Signal) Option {
+	return func(a *App) {
+		a.reloadSignals = signals
+	}
+}
+
+// defaultReloadTimeout 是单次重载的默认超时时间
+const defaultReloadTimeout = 30 * time.Second
+
+// WithReloadTimeout 设置单次重载（包括回滚）的超时时间，默认 30s，<= 0 表示不限制。
+// 超时后卡住的 OnReload 会被放弃，Reload 返回满足 ErrReloadTimeout 的 *ReloadError
+func WithReloadTimeout(d time.Duration) Option {
+	return func(a *App) {
+		a.reloadTimeout = d
+	}
+}
+
+// Reload 按依赖顺序调用所有已启动钩子的 OnReload，用于在不重启进程的情况下重新加载配置。
+// 重载是事务性的：某个钩子失败后不再调用后续钩子，已重载成功的钩子按逆序调用 OnReloadRollback，
+// 并返回 *ReloadError。同一时间只会进行一次重载，应用未运行时返回 ErrNotRunning。
+// 重载受 WithReloadTimeout 限制，关闭流程开始时被中止
+func (a *App) Reload(ctx context.Context) (err error) {
+	a.reloadSem <- struct{}{}
+	defer func() { <-a.reloadSem }()
+	if !a.IsRunning() {
+		return ErrNotRunning
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(a.ctx, cancel)()
+	if a.reloadTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, a.reloadTimeout)
+		defer cancelTimeout()
+	}
+
+	a.log("App reloading...")
+	a.emit(Event{Type: EventAppReloading})
+	begin := time.Now()
+	ctx, span := a.startSpan(ctx, "crab.reload")
+	defer func() {
+		endSpan(span, err)
+		a.emit(Event{Type: EventAppReloaded, Duration: time.Since(begin), Err: err})
+	}()
+
+	a.mu.Lock()
+	started := make(map[int]bool, len(a.started))
+	for _, i := range a.started {
+		started[i] = true
+	}
+	a.mu.Unlock()
+
+	var reloaded []int
+	for _, i := range a.order {
+		hook := a.hooks[i]
+		if !started[i] || hook.OnReload == nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return a.rollbackReload(ctx, &ReloadError{Err: a.reloadTimeoutError(ctx, err)}, reloaded)
+		}
+		if err := a.reloadHook(ctx, i, hook.OnReload); err != nil {
+			return a.rollbackReload(ctx, &ReloadError{Hook: hookName(hook, i), Err: a.reloadTimeoutError(ctx, err)}, reloaded)
+		}
+		reloaded = append(reloaded, i)
+	}
+
+	a.log("App reloaded", "cost", formatCost(time.Since(begin)))
+	return nil
+}
+
+// reloadHook 调用钩子 i 的 OnReload 或 OnReloadRollback
+func (a *App) reloadHook(ctx context.Context, i int, fn types.Runner) error {
+	name := hookName(a.hooks[i], i)
+	ctx, span := a.startHookSpan(ctx, i, PhaseReload)
+	start := time.Now()
+	err := a.callHook(ctx, i, PhaseReload, 0, fn)
+	endSpan(span, err)
+	if err != nil {
+		a.err("Failed to reload component", "hook", name, "phase", PhaseReload, "error", err)
+		return err
+	}
+	a.debug("Reloaded component", "hook", name, "phase", PhaseReload, "cost", formatCost(time.Since(start)))
+	return nil
+}
+
+// reloadTimeoutError 在重载因 WithReloadTimeout 到期而失败时，将 err 包装为 ErrReloadTimeout
+func (a *App) reloadTimeoutError(ctx context.Context, err error) error {
+	if a.reloadTimeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v: %w", ErrReloadTimeout, a.reloadTimeout, err)
+	}
+	return err
+}
+
+// rollbackReload 按逆序回滚已重载成功的钩子。回滚不受原 ctx 取消或超时的影响，
+// 单独受 WithReloadTimeout 限制，关闭流程开始时同样被中止
+func (a *App) rollbackReload(ctx context.Context, re *ReloadError, reloaded []int) error {
+	a.err("App reload failed. Rolling back...", "hook", re.Hook, "phase", PhaseReload, "error", re)
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	defer context.AfterFunc(a.ctx, cancel)()
+	if a.reloadTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, a.reloadTimeout)
+		defer cancelTimeout()
+	}
+	var errs []error
+	for k := len(reloaded) - 1; k >= 0; k-- {
+		i := reloaded[k]
+		if fn := a.hooks[i].OnReloadRollback; fn != nil {
+			if err := a.reloadHook(ctx, i, fn); err != nil {
+				errs = append(errs, newHookError(hookName(a.hooks[i], i), PhaseReload, 0, err))
+			}
+		}
+	}
+	re.Rollback = errors.Join(errs...)
+	return re
+}
@@ -0,0 +1,85 @@
+package crab
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultRetryMaxAttempts    = 3
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 5 * time.Second
+)
+
+// RetryPolicy 定义 OnStart 失败后的重试策略。
+// 所有重试都在启动超时 (WithStartupTimeout) 之内进行，StartTimeout 对每次尝试单独生效
+type RetryPolicy struct {
+	MaxAttempts    int              // 最大尝试次数（包含首次），默认 3
+	InitialBackoff time.Duration    // 首次重试前的等待时间，默认 100ms，之后指数增长
+	MaxBackoff     time.Duration    // 退避时间上限，默认 5s
+	Jitter         float64          // 随机抖动比例 (0~1)，例如 0.2 表示退避时间在 ±20% 范围内浮动
+	Retryable      func(error) bool // 判断错误是否可重试，nil 表示所有错误都可重试
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultRetryMaxBackoff
+	}
+	p.MaxBackoff = max(p.MaxBackoff, p.InitialBackoff)
+	return p
+}
+
+// startWithRetry 执行钩子的 OnStart，失败时按 RetryPolicy 重试，每次尝试都会记录日志
+func (a *App) startWithRetry(ctx context.Context, i int) error {
+	hook := a.hooks[i]
+	if hook.Retry == nil {
+		return a.callHook(ctx, i, PhaseStart, hook.StartTimeout, hook.OnStart)
+	}
+
+	name := hookName(hook, i)
+	policy := hook.Retry.withDefaults()
+	for attempt := 1; ; attempt++ {
+		begin := time.Now()
+		err := a.callHook(ctx, i, PhaseStart, hook.StartTimeout, hook.OnStart)
+		cost := time.Since(begin)
+		if err == nil {
+			if attempt > 1 {
+				a.log("Component start attempt succeeded", "hook", name, "phase", PhaseStart, "attempt", attempt, "cost", formatCost(cost))
+			}
+			return nil
+		}
+
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || (policy.Retryable != nil && !policy.Retryable(err)) {
+			a.err("Component start attempt failed", "hook", name, "phase", PhaseStart, "attempt", attempt, "cost", formatCost(cost), "error", err)
+			if attempt > 1 {
+				return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+			}
+			return err
+		}
+
+		delay := backoffDelay(attempt, policy.InitialBackoff, policy.MaxBackoff, policy.Jitter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			a.err("Component start attempt failed", "hook", name, "phase", PhaseStart, "attempt", attempt, "cost", formatCost(cost), "error", err)
+			return fmt.Errorf("gave up after %d attempts, next retry would exceed the startup deadline: %w", attempt, err)
+		}
+		a.warn("Component start attempt failed, retrying...", "hook", name, "phase", PhaseStart, "attempt", attempt,
+			"max_attempts", policy.MaxAttempts, "cost", formatCost(cost), "backoff", formatCost(delay), "error", err)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attrAttempt.Int(attempt), attribute.String("error", err.Error())))
+		a.emit(Event{Type: EventHookRetry, Hook: name, Phase: PhaseStart, Attempt: attempt, Duration: cost, Err: err, Panic: panicValue(err)})
+		if sleepContext(ctx, delay) != nil {
+			// 启动超时或应用被停止，放弃剩余的重试
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+	}
+}
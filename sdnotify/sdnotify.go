@@ -0,0 +1,204 @@
+// Package sdnotify 将 crab 的生命周期上报给 systemd（Type=notify 服务），实现 sd_notify(3) 协议：
+// 启动完成发送 READY=1，关闭开始发送 STOPPING=1，启动/关闭过程中通过 STATUS= 报告当前组件，
+// 每完成一个组件发送 EXTEND_TIMEOUT_USEC 延长超时，运行期间按 WATCHDOG_USEC 的一半发送 WATCHDOG=1。
+//
+//	app := crab.New(crab.WithObserver(sdnotify.New().Observe))
+package sdnotify
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bang-go/crab"
+)
+
+// 协议消息
+const (
+	Ready     = "READY=1"
+	Stopping  = "STOPPING=1"
+	Reloading = "RELOADING=1"
+	Watchdog  = "WATCHDOG=1"
+)
+
+// DefaultExtendTimeout 每次启动/关闭取得进展时请求 systemd 延长的超时时间
+const DefaultExtendTimeout = 30 * time.Second
+
+// Notify 向 NOTIFY_SOCKET 发送一条或多条状态（如 "READY=1"、"STATUS=..."）。
+// 未设置 NOTIFY_SOCKET（不是由 systemd 启动）时返回 false, nil
+func Notify(states ...string) (bool, error) {
+	return notify(os.Getenv("NOTIFY_SOCKET"), states...)
+}
+
+func notify(socket string, states ...string) (bool, error) {
+	if socket == "" {
+		return false, nil
+	}
+	// 以 @ 开头的名称为 Linux 抽象命名空间，Go 会自动处理
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval 返回 systemd 要求的看门狗超时（WATCHDOG_USEC），未开启看门狗或不是发给本进程时返回 0
+func WatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Option 配置 Notifier
+type Option func(*Notifier)
+
+// WithExtendTimeout 设置每次取得进展时请求延长的超时时间，0 表示不发送 EXTEND_TIMEOUT_USEC
+func WithExtendTimeout(d time.Duration) Option {
+	return func(n *Notifier) {
+		n.extend = d
+	}
+}
+
+// WithErrorHandler 设置发送失败时的回调，默认忽略错误
+func WithErrorHandler(fn func(error)) Option {
+	return func(n *Notifier) {
+		n.onError = fn
+	}
+}
+
+// Notifier 根据生命周期事件向 systemd 发送通知
+type Notifier struct {
+	socket   string
+	watchdog time.Duration // WATCHDOG_USEC，0 表示未开启
+	extend   time.Duration
+	onError  func(error)
+
+	mu   sync.Mutex
+	stop chan struct{} // 关闭后停止看门狗
+}
+
+// New 从环境变量读取 NOTIFY_SOCKET 与 WATCHDOG_USEC 创建 Notifier；
+// 不是由 systemd 启动时 Observe 不做任何事
+func New(opts ...Option) *Notifier {
+	n := &Notifier{
+		socket:   os.Getenv("NOTIFY_SOCKET"),
+		watchdog: WatchdogInterval(),
+		extend:   DefaultExtendTimeout,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// Observe 处理一条生命周期事件，通过 crab.WithObserver(n.Observe) 注册
+func (n *Notifier) Observe(e crab.Event) {
+	if n.socket == "" {
+		return
+	}
+
+	switch e.Type {
+	case crab.EventAppStarting:
+		n.send(status("Starting..."))
+	case crab.EventHookStartBegin:
+		n.send(n.progress(status("Starting " + e.Hook + "..."))...)
+	case crab.EventHookStartEnd, crab.EventHookStopEnd:
+		n.send(n.progress()...)
+	case crab.EventAppStarted:
+		n.send(Ready, status("Running"))
+		n.startWatchdog()
+	case crab.EventRollback:
+		n.send(status(fmt.Sprintf("Start failed: %v, rolling back...", e.Err)))
+	case crab.EventAppDraining:
+		n.send(status("Draining..."))
+	case crab.EventAppStopping:
+		n.stopWatchdog()
+		n.send(Stopping, status("Stopping..."))
+	case crab.EventForcedShutdown:
+		n.send(status("Forcing shutdown..."))
+	case crab.EventHookStopBegin:
+		n.send(n.progress(status("Stopping " + e.Hook + "..."))...)
+	case crab.EventAppStopped:
+		n.stopWatchdog()
+		n.send(status("Stopped"))
+	case crab.EventAppReloading:
+		n.send(Reloading, status("Reloading..."))
+	case crab.EventAppReloaded:
+		if e.Err != nil {
+			n.send(Ready, status(fmt.Sprintf("Running (reload failed: %v)", e.Err)))
+			return
+		}
+		n.send(Ready, status("Running"))
+	}
+}
+
+// progress 在 states 后追加 EXTEND_TIMEOUT_USEC
+func (n *Notifier) progress(states ...string) []string {
+	if n.extend > 0 {
+		states = append(states, "EXTEND_TIMEOUT_USEC="+strconv.FormatInt(n.extend.Microseconds(), 10))
+	}
+	return states
+}
+
+func (n *Notifier) send(states ...string) {
+	if len(states) == 0 {
+		return
+	}
+	if _, err := notify(n.socket, states...); err != nil && n.onError != nil {
+		n.onError(err)
+	}
+}
+
+// startWatchdog 以 WATCHDOG_USEC 的一半为间隔发送 WATCHDOG=1，直到关闭开始
+func (n *Notifier) startWatchdog() {
+	if n.watchdog <= 0 {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	n.stop = stop
+
+	go func() {
+		t := time.NewTicker(n.watchdog / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				n.send(Watchdog)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func (n *Notifier) stopWatchdog() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stop != nil {
+		close(n.stop)
+		n.stop = nil
+	}
+}
+
+func status(s string) string {
+	// STATUS 为单行文本
+	return "STATUS=" + strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,115 @@
+//go:build unix
+
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bang-go/crab"
+)
+
+// listen 在临时目录中绑定 unixgram 套接字，模拟 systemd 的 NOTIFY_SOCKET
+func listen(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, path
+}
+
+// recv 读取下一条通知，按行拆分；skipWatchdog 为 true 时跳过看门狗心跳
+func recv(t *testing.T, conn *net.UnixConn, skipWatchdog bool) []string {
+	t.Helper()
+	buf := make([]byte, 4096)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("no notification received: %v", err)
+		}
+		states := strings.Split(string(buf[:n]), "\n")
+		if skipWatchdog && len(states) == 1 && states[0] == Watchdog {
+			continue
+		}
+		return states
+	}
+}
+
+func TestNotifierObserve(t *testing.T) {
+	conn, path := listen(t)
+	n := New(WithExtendTimeout(2 * time.Second))
+	n.socket = path
+	n.watchdog = 20 * time.Millisecond
+
+	n.Observe(crab.Event{Type: crab.EventHookStartBegin, Hook: "db"})
+	got := recv(t, conn, false)
+	for _, want := range []string{"STATUS=Starting db...", "EXTEND_TIMEOUT_USEC=2000000"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("hook start: got %q, want %q", got, want)
+		}
+	}
+
+	n.Observe(crab.Event{Type: crab.EventAppStarted})
+	got = recv(t, conn, false)
+	for _, want := range []string{Ready, "STATUS=Running"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("app started: got %q, want %q", got, want)
+		}
+	}
+	if got := recv(t, conn, false); !slices.Contains(got, Watchdog) {
+		t.Errorf("watchdog: got %q, want %q", got, Watchdog)
+	}
+
+	n.Observe(crab.Event{Type: crab.EventAppStopping})
+	got = recv(t, conn, true)
+	for _, want := range []string{Stopping, "STATUS=Stopping..."} {
+		if !slices.Contains(got, want) {
+			t.Errorf("app stopping: got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestNotifierWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	n := New()
+	n.Observe(crab.Event{Type: crab.EventAppStarted}) // 不是由 systemd 启动时什么也不做
+	n.stopWatchdog()
+
+	if sent, err := Notify(Ready); sent || err != nil {
+		t.Fatalf("Notify() = %v, %v; want false, nil without NOTIFY_SOCKET", sent, err)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	conn, path := listen(t)
+	t.Setenv("NOTIFY_SOCKET", path)
+	if sent, err := Notify(Ready, "STATUS=Running"); !sent || err != nil {
+		t.Fatalf("Notify() = %v, %v", sent, err)
+	}
+	if got, want := recv(t, conn, false), []string{Ready, "STATUS=Running"}; !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "3000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if got := WatchdogInterval(); got != 3*time.Second {
+		t.Errorf("WatchdogInterval() = %v, want 3s", got)
+	}
+
+	t.Setenv("WATCHDOG_PID", "1")
+	if got := WatchdogInterval(); got != 0 {
+		t.Errorf("WatchdogInterval() for another process = %v, want 0", got)
+	}
+}
@@ -0,0 +1,128 @@
+package crab
+
+import (
+	"context"
+	"errors"
+	"runtime/pprof"
+	"time"
+)
+
+// serveHandle 跟踪一个运行中的 Hook.Serve
+type serveHandle struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// wait 等待 Serve 返回，直到 ctx 结束
+func (h *serveHandle) wait(ctx context.Context) error {
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serve 在独立 goroutine 中运行钩子的 Serve，关闭流程开始后返回 false。
+// Serve 的 Context 不随主 Context 取消，只在关闭该组件时由 stopHook 取消，
+// 以保证服务按依赖逆序停止
+func (a *App) serve(i int) bool {
+	a.serveMu.Lock()
+	defer a.serveMu.Unlock()
+	if a.serveClosed {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(a.ctx))
+	handle := &serveHandle{cancel: cancel, done: make(chan struct{})}
+	a.serving[i] = handle
+	labels := pprof.Labels(labelApp, a.id, labelHook, hookName(a.hooks[i], i), labelPhase, string(PhaseServe))
+	go pprof.Do(ctx, labels, func(ctx context.Context) {
+		a.runServe(ctx, i, handle)
+	})
+	return true
+}
+
+// serveHandle 返回钩子当前的 Serve 句柄，没有运行中的 Serve 时返回 nil
+func (a *App) serveHandle(i int) *serveHandle {
+	a.serveMu.Lock()
+	defer a.serveMu.Unlock()
+	return a.serving[i]
+}
+
+// cancelServe 取消钩子 i 的 Serve 而不等待其返回，用于关闭流程中被跳过的钩子
+func (a *App) cancelServe(i int) {
+	if handle := a.serveHandle(i); handle != nil {
+		handle.cancel()
+	}
+}
+
+// closeServe 标记关闭流程开始，此后监督者不会再拉起新的 Serve，进行中的重启也会被取消
+func (a *App) closeServe() {
+	a.serveMu.Lock()
+	defer a.serveMu.Unlock()
+	a.serveClosed = true
+	a.restartCancel()
+}
+
+// serveClosing 返回关闭流程是否已经开始
+func (a *App) serveClosing() bool {
+	a.serveMu.Lock()
+	defer a.serveMu.Unlock()
+	return a.serveClosed
+}
+
+// runServe 运行 Serve，并根据钩子的 RestartPolicy 决定退出后是否重启
+func (a *App) runServe(ctx context.Context, i int, handle *serveHandle) {
+	defer close(handle.done)
+	hook := a.hooks[i]
+	name := hookName(hook, i)
+	sup := newSupervisor(hook.Restart)
+
+	for {
+		begin := time.Now()
+		err := safeCall(ctx, hook.Serve)
+
+		if ctx.Err() != nil {
+			// 关闭流程主动取消，属于正常退出
+			if err != nil && !errors.Is(err, context.Canceled) {
+				a.warn("Component serve returned error while stopping", "hook", name, "phase", PhaseServe, "error", err)
+			}
+			return
+		}
+
+		cost := time.Since(begin)
+		a.emitHook(EventHookServeExit, i, PhaseServe, cost, err)
+		if !sup.shouldRestart(err) {
+			if err == nil {
+				a.log("Component serve exited", "hook", name, "phase", PhaseServe)
+				return
+			}
+			a.err("Component serve failed", "hook", name, "phase", PhaseServe, "error", err)
+			a.fail(newHookError(name, PhaseServe, cost, err))
+			return
+		}
+
+		if err != nil {
+			a.err("Component serve failed", "hook", name, "phase", PhaseServe, "error", err)
+		} else {
+			a.log("Component serve exited", "hook", name, "phase", PhaseServe)
+		}
+		if err := a.supervise(ctx, i, sup, err); err != nil {
+			a.err("Component restart limit exceeded, shutting down...", "hook", name, "phase", PhaseServe, "error", err)
+			a.fail(newHookError(name, PhaseServe, cost, err))
+			return
+		}
+		if ctx.Err() != nil || a.restartCtx.Err() != nil {
+			return
+		}
+	}
+}
+
+// fail 上报导致应用关闭的组件错误，只保留第一个
+func (a *App) fail(err error) {
+	select {
+	case a.serveErr <- err:
+	default: // 已有其他组件触发关闭
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -98,15 +99,15 @@ func (sm *ShutdownManager) Shutdown(ctx context.Context) error {
 		close(results)
 	}()
 
-	var errors []error
+	var errs []error
 	for res := range results {
 		if res.err != nil {
-			errors = append(errors, fmt.Errorf("app %s shutdown failed: %w", res.appID, res.err))
+			errs = append(errs, fmt.Errorf("app %s shutdown failed: %w", res.appID, res.err))
 		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("shutdown errors: %v", errors)
+	if len(errs) > 0 {
+		return fmt.Errorf("shutdown errors: %w", errors.Join(errs...))
 	}
 
 	return nil
@@ -0,0 +1,146 @@
+package crab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ShutdownMode 决定关闭期限（WithShutdownTimeout 或 Stop 传入的 Context）到期后如何处理剩余的钩子
+type ShutdownMode int
+
+const (
+	// ShutdownAbort 不再调用剩余钩子的 OnStop，将其记录在 ShutdownError.Skipped 中（默认）
+	ShutdownAbort ShutdownMode = iota
+	// ShutdownBestEffort 继续按顺序关闭剩余钩子，每个钩子单独获得 Grace 时长
+	ShutdownBestEffort
+)
+
+// defaultShutdownGrace 是 ShutdownBestEffort 模式下每个钩子的默认宽限时间
+const defaultShutdownGrace = time.Second
+
+// ShutdownPolicy 描述关闭期限到期后的处理策略
+type ShutdownPolicy struct {
+	Mode  ShutdownMode
+	Grace time.Duration // ShutdownBestEffort 模式下每个剩余钩子的宽限时间，默认 1s
+}
+
+// WithShutdownPolicy 设置关闭期限到期后的处理策略。
+// 例如慢速的 tracer flush 耗尽了关闭时间，ShutdownBestEffort 仍会关闭其后的连接池、提交消费位点
+func WithShutdownPolicy(p ShutdownPolicy) Option {
+	return func(a *App) {
+		if p.Grace <= 0 {
+			p.Grace = defaultShutdownGrace
+		}
+		a.shutdownPolicy = p
+	}
+}
+
+// shutdownTracker 记录关闭流程中每个钩子的结果，并发关闭时由 mu 保护
+type shutdownTracker struct {
+	mu        sync.Mutex
+	completed []string
+	failed    []*HookError
+	skipped   []string
+	aborted   error
+	overrun   sync.Once
+}
+
+// result 汇总关闭结果，所有钩子都已完成时返回 nil
+func (t *shutdownTracker) result() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.failed) == 0 && len(t.skipped) == 0 {
+		return nil
+	}
+	return &ShutdownError{
+		Completed: t.completed,
+		Failed:    t.failed,
+		Skipped:   t.skipped,
+		Aborted:   t.aborted,
+	}
+}
+
+// skip 记录被跳过的钩子，aborted 只保留第一个中止原因
+func (a *App) skip(ctx context.Context, t *shutdownTracker, name string, aborted error) {
+	trace.SpanFromContext(ctx).AddEvent("hook skipped", trace.WithAttributes(attrHook.String(name)))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.skipped = append(t.skipped, name)
+	if t.aborted == nil {
+		t.aborted = aborted
+	}
+}
+
+// stopOne 关闭单个钩子并记录结果。关闭期限已过时按 ShutdownPolicy 跳过该钩子或为其分配宽限时间；
+// 进入强制关闭后只在强制关闭时限内关闭关键钩子。被跳过的钩子不调用 OnStop，但仍会取消其 Serve 的 Context
+func (a *App) stopOne(ctx context.Context, i int, t *shutdownTracker) {
+	name := hookName(a.hooks[i], i)
+	if cause := ctx.Err(); cause != nil {
+		switch forceCtx := a.forcedContext(); {
+		case forceCtx != nil:
+			if !a.hooks[i].Critical {
+				a.warn("Forced shutdown, skipping non-critical component", "hook", name, "phase", PhaseStop)
+				a.cancelServe(i)
+				a.skip(ctx, t, name, fmt.Errorf("%w: %w", ErrShutdownAborted, context.Cause(ctx)))
+				return
+			}
+			// 关键钩子共用强制关闭的时限，保留原 ctx 中的 span
+			deadline, _ := forceCtx.Deadline()
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(context.WithoutCancel(ctx), deadline)
+			defer cancel()
+		case a.shutdownPolicy.Mode != ShutdownBestEffort:
+			a.err("Shutdown deadline exceeded, skipping component", "hook", name, "phase", PhaseStop)
+			a.cancelServe(i)
+			a.skip(ctx, t, name, fmt.Errorf("%w: %w", ErrShutdownAborted, cause))
+			return
+		default:
+			t.overrun.Do(func() {
+				a.warn("Shutdown deadline exceeded, continuing with grace period", "grace", formatCost(a.shutdownPolicy.Grace))
+			})
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), a.shutdownPolicy.Grace)
+			defer cancel()
+		}
+	} else if a.hooks[i].Critical {
+		var cancel context.CancelFunc
+		ctx, cancel = a.criticalContext(ctx)
+		defer cancel()
+	}
+
+	ctx, checkModule, cancel := a.moduleContext(ctx, i, PhaseStop)
+	defer cancel()
+	he := a.stopHook(ctx, i)
+	if he != nil {
+		he.Err = checkModule(he.Err)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if he != nil {
+		t.failed = append(t.failed, he)
+		return
+	}
+	t.completed = append(t.completed, name)
+}
+
+// criticalContext 为正在关闭的关键钩子派生 Context：关闭流程因强制关闭被取消时，
+// 钩子不会随之中断，而是继续执行到强制关闭时限
+func (a *App) criticalContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	cctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		if forceCtx := a.forcedContext(); forceCtx != nil && errors.Is(context.Cause(ctx), ErrForcedShutdown) {
+			context.AfterFunc(forceCtx, func() { cancel(context.Cause(forceCtx)) })
+			return
+		}
+		cancel(context.Cause(ctx))
+	})
+	return cctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
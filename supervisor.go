@@ -0,0 +1,262 @@
+package crab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bang-go/crab/pkg/types"
+)
+
+// RestartMode 定义 Serve 返回后是否重启组件
+type RestartMode int
+
+const (
+	RestartNever     RestartMode = iota // 不重启：Serve 返回错误即关闭应用（默认）
+	RestartOnFailure                    // 仅在 Serve 返回错误或 panic 时重启
+	RestartAlways                       // Serve 无论因何返回都重启
+)
+
+// RestartStrategy 定义组件重启时波及的范围
+type RestartStrategy int
+
+const (
+	OneForOne RestartStrategy = iota // 只重启退出的组件
+	OneForAll                        // 同时重启（直接或间接）依赖该组件的所有组件
+)
+
+const (
+	defaultRestartInitialBackoff = 100 * time.Millisecond
+	defaultRestartMaxBackoff     = 30 * time.Second
+	defaultRestartMaxRestarts    = 5
+	defaultRestartWindow         = time.Minute
+)
+
+// errAppStopping 表示重启因关闭流程开始而中止
+var errAppStopping = errors.New("app is stopping")
+
+// RestartPolicy 定义带 Serve 的组件的重启策略。
+// 重启会依次调用组件的 OnStop、OnStart，再重新运行 Serve；
+// 在 Window 时间窗口内重启超过 MaxRestarts 次视为崩溃循环，升级为整个应用的关闭
+type RestartPolicy struct {
+	Mode           RestartMode
+	Strategy       RestartStrategy
+	InitialBackoff time.Duration // 首次重启前的等待时间，默认 100ms，之后指数增长
+	MaxBackoff     time.Duration // 退避时间上限，默认 30s
+	MaxRestarts    int           // 时间窗口内允许的最大重启次数，默认 5
+	Window         time.Duration // 统计重启次数的时间窗口，默认 1 分钟
+}
+
+// Supervise 创建一个受监督的长期运行组件，fn 退出后按 policy 重启。
+//
+// Example:
+//
+//	app.Add(crab.Supervise("order-consumer", consumer.Run, crab.RestartPolicy{
+//	    Mode: crab.RestartOnFailure,
+//	}))
+func Supervise(name string, fn types.Runner, policy RestartPolicy) Hook {
+	return Hook{Name: name, Serve: fn, Restart: &policy}
+}
+
+// supervisor 记录单个组件的重启历史
+type supervisor struct {
+	policy   RestartPolicy
+	restarts []time.Time
+}
+
+func newSupervisor(p *RestartPolicy) *supervisor {
+	if p == nil || p.Mode == RestartNever {
+		return nil
+	}
+	policy := *p
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultRestartInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultRestartMaxBackoff
+	}
+	policy.MaxBackoff = max(policy.MaxBackoff, policy.InitialBackoff)
+	if policy.MaxRestarts <= 0 {
+		policy.MaxRestarts = defaultRestartMaxRestarts
+	}
+	if policy.Window <= 0 {
+		policy.Window = defaultRestartWindow
+	}
+	return &supervisor{policy: policy}
+}
+
+// shouldRestart 判断 Serve 以 err 返回后是否需要重启
+func (s *supervisor) shouldRestart(err error) bool {
+	if s == nil {
+		return false
+	}
+	return s.policy.Mode == RestartAlways || err != nil
+}
+
+// next 登记一次重启并返回退避时间，超出时间窗口内的重启预算时返回 false
+func (s *supervisor) next(now time.Time) (int, time.Duration, bool) {
+	recent := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) < s.policy.Window {
+			recent = append(recent, t)
+		}
+	}
+	s.restarts = recent
+	if len(s.restarts) >= s.policy.MaxRestarts {
+		return len(s.restarts), 0, false
+	}
+	s.restarts = append(s.restarts, now)
+	attempt := len(s.restarts)
+	return attempt, backoffDelay(attempt, s.policy.InitialBackoff, s.policy.MaxBackoff, 0), true
+}
+
+// supervise 按退避策略重启组件，直到成功、ctx 被取消或重启预算耗尽。
+// 只有预算耗尽时返回错误，调用方应将其升级为应用关闭
+func (a *App) supervise(ctx context.Context, i int, sup *supervisor, cause error) error {
+	name := hookName(a.hooks[i], i)
+	if cause == nil {
+		cause = errors.New("serve exited")
+	}
+	// 关闭流程开始时中止退避等待与进行中的重启
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(a.restartCtx, cancel)()
+
+	for {
+		attempt, delay, ok := sup.next(time.Now())
+		if !ok {
+			return fmt.Errorf("restarted %d times within %v: %w", attempt, sup.policy.Window, cause)
+		}
+
+		a.warn("Restarting component...", "hook", name, "phase", PhaseServe, "attempt", attempt, "backoff", formatCost(delay))
+		if sleepContext(ctx, delay) != nil {
+			return nil
+		}
+
+		start := time.Now()
+		restartCtx, span := a.startSpan(ctx, "crab.restart "+name, attrHook.String(name), attrAttempt.Int(attempt))
+		err := a.restartComponent(restartCtx, i, sup.policy.Strategy)
+		endSpan(span, err)
+		cost := time.Since(start)
+		if err == nil {
+			a.log("Restarted component", "hook", name, "phase", PhaseServe, "attempt", attempt, "cost", formatCost(cost))
+			a.emit(Event{Type: EventHookRestart, Hook: name, Phase: PhaseServe, Attempt: attempt, Duration: cost})
+			return nil
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		a.emit(Event{Type: EventHookRestart, Hook: name, Phase: PhaseServe, Attempt: attempt, Duration: cost, Err: err, Panic: panicValue(err)})
+		a.err("Failed to restart component", "hook", name, "phase", PhaseServe, "attempt", attempt, "error", err)
+		cause = err
+	}
+}
+
+// restartComponent 重启组件 i（其 Serve 已返回），OneForAll 时一并重启依赖它的组件：
+// 先按依赖逆序停止，再按依赖顺序启动；任一组件启动失败时停止本轮已启动的组件并返回错误。
+// 停止后尚未重新启动的组件记录在 a.down 中，下一轮重启与关闭流程不会重复停止它们。
+// 关闭流程开始后不再调用任何 OnStop / OnStart，已重新启动的组件留给关闭流程按依赖逆序关闭
+func (a *App) restartComponent(ctx context.Context, i int, strategy RestartStrategy) error {
+	a.restartMu.Lock()
+	defer a.restartMu.Unlock()
+
+	group := []int{i}
+	if strategy == OneForAll {
+		group = append(group, a.dependentsOf(i)...)
+	}
+
+	for k := len(group) - 1; k >= 0; k-- {
+		j := group[k]
+		if a.serveClosing() {
+			return newHookError(hookName(a.hooks[j], j), PhaseStop, 0, errAppStopping)
+		}
+		if a.down[j] {
+			continue
+		}
+		a.setDown(j, true)
+		if j == i {
+			// 自身的 Serve 已返回，只需清理
+			a.markHook(i, func(s *hookStatus) { s.state = HookStopping })
+			if stop := a.hooks[i].OnStop; stop != nil {
+				if err := a.callHook(ctx, i, PhaseStop, a.hooks[i].StopTimeout, types.Runner(stop)); err != nil {
+					a.err("Failed to stop component", "hook", hookName(a.hooks[i], i), "phase", PhaseStop, "error", err)
+				}
+			}
+			a.markHook(i, func(s *hookStatus) { s.state = HookStopped })
+			continue
+		}
+		if hasStop(a.hooks[j]) {
+			_ = a.stopHook(ctx, j)
+		}
+	}
+
+	for k, j := range group {
+		hook := a.hooks[j]
+		err := ctx.Err()
+		if err == nil && a.serveClosing() {
+			err = errAppStopping
+		}
+		if err == nil && hook.OnStart != nil {
+			err = a.startWithRetry(ctx, j)
+		}
+		if err == nil {
+			a.setDown(j, false)
+			a.markHook(j, func(s *hookStatus) { s.state = HookRunning })
+			if j != i && hook.Serve != nil && !a.serve(j) {
+				err = errAppStopping
+			}
+		}
+		if err != nil {
+			if !a.serveClosing() {
+				for r := k - 1; r >= 0; r-- {
+					if s := group[r]; s != i && hasStop(a.hooks[s]) {
+						_ = a.stopHook(ctx, s)
+						a.setDown(s, true)
+					}
+				}
+			}
+			return newHookError(hookName(hook, j), PhaseStart, 0, err)
+		}
+	}
+	return nil
+}
+
+// setDown 记录组件 i 是否被监督者停止，调用方需持有 restartMu
+func (a *App) setDown(i int, down bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if down {
+		a.down[i] = true
+	} else {
+		delete(a.down, i)
+	}
+}
+
+// dependentsOf 按启动顺序返回直接或间接依赖组件 i 且已启动的组件
+func (a *App) dependentsOf(i int) []int {
+	a.mu.Lock()
+	started := make(map[int]bool, len(a.started))
+	for _, j := range a.started {
+		started[j] = true
+	}
+	a.mu.Unlock()
+
+	affected := map[int]bool{i: true}
+	var result []int
+	for _, j := range a.order {
+		if j == i {
+			continue
+		}
+		for _, d := range a.deps[j] {
+			if affected[d] {
+				affected[j] = true
+				break
+			}
+		}
+		if affected[j] && started[j] {
+			result = append(result, j)
+		}
+	}
+	return result
+}
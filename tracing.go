@@ -0,0 +1,72 @@
+package crab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracerName = "github.com/bang-go/crab"
+
+// 链路追踪使用的属性名
+const (
+	attrAppID   = attribute.Key("crab.app_id")
+	attrHook    = attribute.Key("crab.hook")
+	attrPhase   = attribute.Key("crab.phase")
+	attrAttempt = attribute.Key("crab.attempt")
+	attrTimeout = attribute.Key("crab.timeout")
+)
+
+// WithTracerProvider 开启启动与关闭流程的链路追踪：
+// 启动时创建根 span "crab.startup"，每个 OnStart 一个子 span；
+// 关闭时创建 "crab.shutdown"，每个关闭的钩子一个子 span。
+// 钩子收到的 ctx 携带对应的 span，组件自身初始化时创建的 span 会嵌套在其下
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(a *App) {
+		a.tracer = tp.Tracer(tracerName)
+	}
+}
+
+func defaultTracer() trace.Tracer {
+	return noop.NewTracerProvider().Tracer(tracerName)
+}
+
+// startSpan 创建 crab 的 span，并附带应用 ID
+func (a *App) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attrAppID.String(a.id))
+	return a.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// startHookSpan 为钩子 i 的某个阶段创建子 span
+func (a *App) startHookSpan(ctx context.Context, i int, phase Phase) (context.Context, trace.Span) {
+	name := hookName(a.hooks[i], i)
+	return a.startSpan(ctx, fmt.Sprintf("crab.%s %s", phase, name), attrHook.String(name), attrPhase.String(string(phase)))
+}
+
+// endSpan 根据 err 设置 span 状态后结束 span，panic 与超时会额外记录为 span 事件
+func endSpan(span trace.Span, err error) {
+	defer span.End()
+	if err == nil {
+		span.SetStatus(codes.Ok, "")
+		return
+	}
+
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		span.AddEvent("panic", trace.WithAttributes(
+			attribute.String("exception.type", "panic"),
+			attribute.String("exception.message", fmt.Sprint(pe.Value)),
+			attribute.String("exception.stacktrace", string(pe.Stack)),
+		))
+	}
+	if errors.Is(err, ErrHookTimeout) || errors.Is(err, ErrStartupTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		span.SetAttributes(attrTimeout.Bool(true))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}